@@ -0,0 +1,434 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	devpodconfig "github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/loft-sh/devpod/pkg/types"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// adoptKeysConfig is the compose config holding the DevPod public key of an adopted service
+	adoptKeysConfig = "devpod-authorized-keys"
+	// adoptKeysTarget is read by sshd by default and leaves the service's own authorized_keys alone
+	adoptKeysTarget = "/root/.ssh/authorized_keys2"
+)
+
+// machineIDPattern matches the machine IDs DevPod accepts
+var machineIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+var (
+	adoptService  string
+	adoptProvider string
+	adoptContext  string
+	adoptTimeout  time.Duration
+)
+
+// adoptCmd represents the adopt command
+var adoptCmd = &cobra.Command{
+	Use:   "adopt <composeId>",
+	Short: "Manage an existing Dokploy compose service as a DevPod machine",
+	Long: `Turn a hand-built Dokploy compose service into a DevPod machine named after the service.
+
+The service must publish the SSH port (container port 22) on a fixed host port and allow root
+logins with a key. adopt generates the machine's SSH key, injects the public key through a
+compose config mounted at /root/.ssh/authorized_keys2, adds the x-devpod ownership marker and
+redeploys. Once SSH works with the new key, the DevPod machine state is written, so that
+DevPod's status, command, stop and delete work on the service. Use the machine with
+"devpod up <source> --machine <name>".
+
+The compose file is otherwise kept as it is; upgrade and resize skip adopted services.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAdopt(args[0])
+	},
+}
+
+func init() {
+	adoptCmd.Flags().StringVar(&adoptService, "service", "", "the compose service running sshd (default: the service publishing container port 22)")
+	adoptCmd.Flags().StringVar(&adoptProvider, "provider", "dokploy", "name of this provider in DevPod")
+	adoptCmd.Flags().StringVar(&adoptContext, "context", "", "DevPod context to add the machine to (default: the current context)")
+	adoptCmd.Flags().DurationVar(&adoptTimeout, "timeout", 0, "how long to wait for the redeploy and SSH (default: DOKPLOY_START_TIMEOUT)")
+	rootCmd.AddCommand(adoptCmd)
+}
+
+func runAdopt(composeID string) (err error) {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	timeout := opts.StartTimeout
+	if adoptTimeout > 0 {
+		timeout = adoptTimeout
+	}
+
+	dokployClient := dokploy.NewClient(opts, logger)
+
+	compose, err := dokployClient.GetCompose(composeID)
+	if err != nil {
+		return fmt.Errorf("failed to get compose service %s: %w", composeID, err)
+	}
+	machineID := compose.Name
+
+	// Validate the service before changing anything
+	if marker, err := parseWorkspaceMarker(compose.ComposeFile); err != nil {
+		return err
+	} else if marker != nil && marker.ManagedBy == providerMarker {
+		return fmt.Errorf("compose service %s is already managed by the DevPod provider as machine %s", composeID, marker.MachineID)
+	}
+	if compose.SourceType != "" && compose.SourceType != "raw" {
+		return fmt.Errorf("compose service %s reads its compose file from %s; only services with a raw compose file can be adopted", composeID, compose.SourceType)
+	}
+	if !machineIDPattern.MatchString(machineID) {
+		return fmt.Errorf("compose service name '%s' is not a valid DevPod machine name (lowercase letters, digits and dashes); rename it in Dokploy first", machineID)
+	}
+	// delete and gc only look for workspaces in DOKPLOY_PROJECT_NAME
+	projectID, err := findProjectID(dokployClient, opts.DokployProjectName)
+	if err != nil {
+		return err
+	}
+	inProject, err := composeInProject(dokployClient, projectID, compose)
+	if err != nil {
+		return err
+	}
+	if !inProject {
+		return fmt.Errorf("compose service %s is not in project '%s'; set DOKPLOY_PROJECT_NAME to its project first", composeID, opts.DokployProjectName)
+	}
+	byName, err := dokployClient.GetComposeByName(machineID)
+	if err != nil {
+		return fmt.Errorf("failed to look up compose services named '%s': %w", machineID, err)
+	}
+	if byName.ComposeID != composeID {
+		return fmt.Errorf("another compose service is named '%s'; rename one of them in Dokploy first", machineID)
+	}
+
+	service, sshPort, err := findSSHService(compose.ComposeFile, adoptService)
+	if err != nil {
+		return err
+	}
+
+	// Services on a remote server publish SSH on that server
+	parsedURL, err := url.Parse(opts.DokployServerURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse server URL: %w", err)
+	}
+	sshHost := parsedURL.Hostname()
+	markerHost := ""
+	if compose.ServerID != "" {
+		server, err := dokployClient.GetServer(compose.ServerID)
+		if err != nil {
+			return fmt.Errorf("failed to get server %s: %w", compose.ServerID, err)
+		}
+		sshHost, markerHost = server.IPAddress, server.IPAddress
+	}
+	if !checkSSHReadiness(sshHost, sshPort, logger) {
+		return fmt.Errorf("no SSH server answers on %s:%d; deploy compose service %s and make sure sshd runs in service %s", sshHost, sshPort, composeID, service)
+	}
+	logger.Infof("✓ Service %s exposes SSH on %s:%d", service, sshHost, sshPort)
+
+	// Locate the DevPod machine state
+	context := adoptContext
+	if context == "" {
+		devpodConfig, err := devpodconfig.LoadConfig("", "")
+		if err != nil {
+			return fmt.Errorf("failed to load DevPod config: %w", err)
+		}
+		context = devpodConfig.DefaultContext
+	}
+	if !provider.ProviderExists(context, adoptProvider) {
+		return fmt.Errorf("provider '%s' not found in DevPod context '%s'; add it first or pass --provider", adoptProvider, context)
+	}
+	if provider.MachineExists(context, machineID) {
+		return fmt.Errorf("DevPod machine %s already exists in context '%s'", machineID, context)
+	}
+	machineFolder, err := provider.GetMachineDir(context, machineID)
+	if err != nil {
+		return fmt.Errorf("failed to locate the DevPod machine folder: %w", err)
+	}
+
+	// The key pair is generated in the machine folder, which is removed again if adopting fails
+	defer func() {
+		if err != nil {
+			os.RemoveAll(machineFolder)
+		}
+	}()
+	publicKey, err := loadMachinePublicKey(machineFolder, logger)
+	if err != nil {
+		return err
+	}
+	privateKey, err := ssh.GetPrivateKeyRawBase(machineFolder)
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	adoptedFile, err := adoptComposeFile(compose.ComposeFile, service, workspaceMarker{
+		ManagedBy: providerMarker,
+		MachineID: machineID,
+		SSHPort:   sshPort,
+		SSHHost:   markerHost,
+		Adopted:   true,
+		Service:   service,
+	}, publicKey)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Injecting the DevPod key into %s and redeploying compose service %s...", service, composeID)
+	if err := dokployClient.SaveComposeFile(dokploy.SaveComposeFileRequest{
+		ComposeID:     composeID,
+		DockerCompose: adoptedFile,
+		Env:           compose.Env,
+	}); err != nil {
+		return fmt.Errorf("failed to save Docker Compose file: %w", err)
+	}
+
+	address := net.JoinHostPort(sshHost, strconv.Itoa(sshPort))
+	err = redeployCompose(dokployClient, compose, timeout, logger)
+	if err == nil {
+		err = waitForSSHLogin(address, privateKey, timeout, logger)
+	}
+	if err != nil {
+		// Put the service back the way it was
+		logger.Warnf("Adopting failed, restoring the original compose file of %s", composeID)
+		if restoreErr := dokployClient.SaveComposeFile(dokploy.SaveComposeFileRequest{
+			ComposeID:     composeID,
+			DockerCompose: compose.ComposeFile,
+			Env:           compose.Env,
+		}); restoreErr != nil {
+			logger.Errorf("Failed to restore the original compose file: %v", restoreErr)
+		} else if restoreErr := dokployClient.DeployCompose(dokploy.DeployComposeRequest{ComposeID: composeID}); restoreErr != nil {
+			logger.Errorf("Failed to redeploy the original compose file: %v", restoreErr)
+		}
+		return err
+	}
+	logger.Infof("✓ SSH login as root with the DevPod key works on %s", address)
+
+	if err := provider.SaveMachineConfig(&provider.Machine{
+		ID:                machineID,
+		Provider:          provider.MachineProviderConfig{Name: adoptProvider},
+		CreationTimestamp: types.Now(),
+		Context:           context,
+	}); err != nil {
+		return fmt.Errorf("failed to save DevPod machine state: %w", err)
+	}
+
+	logger.Infof("✓ Compose service %s adopted as DevPod machine %s", composeID, machineID)
+	logger.Infof("Use it with: devpod up <source> --machine %s", machineID)
+	return nil
+}
+
+// composeInProject reports whether a compose service belongs to a project
+func composeInProject(dokployClient *dokploy.Client, projectID string, compose *dokploy.Compose) (bool, error) {
+	if projectID == "" {
+		return false, nil
+	}
+	if compose.ProjectID != "" {
+		return compose.ProjectID == projectID, nil
+	}
+	matches, err := dokployClient.FindComposesInProject(projectID, compose.Name)
+	if err != nil {
+		return false, err
+	}
+	for _, match := range matches {
+		if match.ComposeID == compose.ComposeID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// findSSHService returns the service publishing container port 22 and its host port. With a
+// service name only that service is considered.
+func findSSHService(composeFile, serviceName string) (string, int, error) {
+	var document struct {
+		Services map[string]struct {
+			Ports []interface{} `yaml:"ports"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal([]byte(composeFile), &document); err != nil {
+		return "", 0, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if serviceName != "" {
+		if _, ok := document.Services[serviceName]; !ok {
+			return "", 0, fmt.Errorf("service %s not found in the compose file", serviceName)
+		}
+	}
+
+	var found []string
+	foundPort := 0
+	for name, service := range document.Services {
+		if serviceName != "" && name != serviceName {
+			continue
+		}
+		for _, entry := range service.Ports {
+			published := publishedSSHPort(entry)
+			if published == "" {
+				continue
+			}
+			port, err := strconv.Atoi(published)
+			if err != nil || port <= 0 {
+				return "", 0, fmt.Errorf("service %s publishes SSH on '%s'; adopt needs a fixed host port", name, published)
+			}
+			found = append(found, name)
+			foundPort = port
+			break
+		}
+	}
+
+	switch {
+	case len(found) == 0 && serviceName != "":
+		return "", 0, fmt.Errorf("service %s does not publish container port 22", serviceName)
+	case len(found) == 0:
+		return "", 0, fmt.Errorf("no service publishes container port 22; publish the SSH port on a fixed host port first")
+	case len(found) > 1:
+		return "", 0, fmt.Errorf("services %s all publish container port 22; pick one with --service", strings.Join(found, ", "))
+	}
+	return found[0], foundPort, nil
+}
+
+// publishedSSHPort returns the host port of a ports entry mapping container port 22, or "" for
+// other entries. Both the short ("[ip:]host:22[/tcp]") and the long syntax are understood.
+func publishedSSHPort(entry interface{}) string {
+	switch port := entry.(type) {
+	case string:
+		port = strings.TrimSuffix(port, "/tcp")
+		parts := strings.Split(port, ":")
+		if len(parts) < 2 || parts[len(parts)-1] != "22" {
+			return ""
+		}
+		return parts[len(parts)-2]
+	case map[string]interface{}:
+		if fmt.Sprint(port["target"]) != "22" || (port["protocol"] != nil && port["protocol"] != "tcp") {
+			return ""
+		}
+		if port["published"] == nil {
+			return "unset"
+		}
+		return fmt.Sprint(port["published"])
+	}
+	return ""
+}
+
+// adoptComposeFile adds the x-devpod marker and the DevPod public key to a hand-built compose
+// file. The key is an inline compose config mounted into the SSH service; everything else is kept.
+func adoptComposeFile(composeFile, service string, marker workspaceMarker, publicKey string) (string, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(composeFile), &document); err != nil {
+		return "", fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("compose file is not a YAML mapping")
+	}
+	root := document.Content[0]
+
+	var markerNode yaml.Node
+	if err := markerNode.Encode(marker); err != nil {
+		return "", fmt.Errorf("failed to encode workspace marker: %w", err)
+	}
+	setMappingValue(root, "x-devpod", &markerNode)
+
+	configs := mappingChild(root, "configs", yaml.MappingNode)
+	setMappingValue(configs, adoptKeysConfig, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		scalarNode("content"), scalarNode(publicKey),
+	}})
+
+	services := mappingChild(root, "services", yaml.MappingNode)
+	serviceNode := mappingChild(services, service, yaml.MappingNode)
+	serviceConfigs := mappingChild(serviceNode, "configs", yaml.SequenceNode)
+	kept := serviceConfigs.Content[:0]
+	for _, entry := range serviceConfigs.Content {
+		if entry.Value == adoptKeysConfig || (entry.Kind == yaml.MappingNode && mappingValue(entry, "source") != nil && mappingValue(entry, "source").Value == adoptKeysConfig) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	serviceConfigs.Content = append(kept, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+		scalarNode("source"), scalarNode(adoptKeysConfig),
+		scalarNode("target"), scalarNode(adoptKeysTarget),
+	}})
+
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return "", fmt.Errorf("failed to encode compose file: %w", err)
+	}
+	encoder.Close()
+	return buffer.String(), nil
+}
+
+// waitForSSHLogin retries an SSH login as root with the machine key until it works or the timeout ends
+func waitForSSHLogin(address string, privateKey []byte, timeout time.Duration, logger *logrus.Logger) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		sshClient, err := ssh.NewSSHClient("root", address, privateKey)
+		if err == nil {
+			sshClient.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("SSH login as root with the DevPod key failed on %s: %w; make sure sshd permits root logins with a key", address, err)
+		}
+		logger.Debugf("SSH login on %s not possible yet: %v", address, err)
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// mappingValue returns the value of a key in a YAML mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingChild returns the value of a key in a YAML mapping node, adding an empty node of the
+// given kind when the key is missing or null
+func mappingChild(mapping *yaml.Node, key string, kind yaml.Kind) *yaml.Node {
+	child := mappingValue(mapping, key)
+	if child == nil || child.Tag == "!!null" {
+		child = &yaml.Node{Kind: kind}
+		setMappingValue(mapping, key, child)
+	}
+	return child
+}
+
+// setMappingValue sets a key of a YAML mapping node, appending it when missing
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, scalarNode(key), value)
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
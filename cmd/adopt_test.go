@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPublishedSSHPort(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry interface{}
+		want  string
+	}{
+		{name: "short syntax", entry: "2200:22", want: "2200"},
+		{name: "short syntax with IP", entry: "0.0.0.0:2200:22", want: "2200"},
+		{name: "short syntax with protocol", entry: "2200:22/tcp", want: "2200"},
+		{name: "other container port", entry: "8080:80"},
+		{name: "container port only", entry: "22"},
+		{name: "UDP", entry: "2200:22/udp"},
+		{name: "long syntax", entry: map[string]interface{}{"target": 22, "published": 2200}, want: "2200"},
+		{name: "long syntax with protocol", entry: map[string]interface{}{"target": 22, "published": "2200", "protocol": "tcp"}, want: "2200"},
+		{name: "long syntax without published port", entry: map[string]interface{}{"target": 22}, want: "unset"},
+		{name: "long syntax UDP", entry: map[string]interface{}{"target": 22, "published": 2200, "protocol": "udp"}},
+		{name: "long syntax other port", entry: map[string]interface{}{"target": 80, "published": 8080}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := publishedSSHPort(tt.entry); got != tt.want {
+				t.Errorf("publishedSSHPort(%v) = %q, want %q", tt.entry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindSSHService(t *testing.T) {
+	const twoServices = `services:
+  app:
+    ports:
+      - "2200:22"
+  db:
+    ports:
+      - target: 22
+        published: 2201
+`
+
+	tests := []struct {
+		name        string
+		composeFile string
+		service     string
+		wantService string
+		wantPort    int
+		wantErr     bool
+	}{
+		{name: "one service", composeFile: "services:\n  app:\n    ports:\n      - \"8080:80\"\n      - \"2200:22\"\n", wantService: "app", wantPort: 2200},
+		{name: "picked service", composeFile: twoServices, service: "db", wantService: "db", wantPort: 2201},
+		{name: "several services", composeFile: twoServices, wantErr: true},
+		{name: "unknown service", composeFile: twoServices, service: "web", wantErr: true},
+		{name: "service without SSH", composeFile: "services:\n  app:\n    ports:\n      - \"8080:80\"\n", service: "app", wantErr: true},
+		{name: "no SSH", composeFile: "services:\n  app:\n    image: nginx\n", wantErr: true},
+		{name: "random host port", composeFile: "services:\n  app:\n    ports:\n      - target: 22\n", wantErr: true},
+		{name: "invalid YAML", composeFile: "services: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, port, err := findSSHService(tt.composeFile, tt.service)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("findSSHService() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if service != tt.wantService || port != tt.wantPort {
+				t.Errorf("findSSHService() = %s, %d, want %s, %d", service, port, tt.wantService, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestAdoptComposeFile(t *testing.T) {
+	const publicKey = "ssh-ed25519 AAAAC3Nza devpod"
+	marker := workspaceMarker{ManagedBy: providerMarker, MachineID: "app", SSHPort: 2200, SSHHost: "10.0.0.5", Adopted: true, Service: "app"}
+
+	tests := []struct {
+		name        string
+		composeFile string
+		wantConfigs int
+		wantErr     bool
+	}{
+		{
+			name:        "plain service",
+			composeFile: "services:\n  app:\n    image: my/app # keep this comment\n    ports:\n      - \"2200:22\"\n",
+			wantConfigs: 1,
+		},
+		{
+			name: "existing configs",
+			composeFile: `services:
+  app:
+    image: my/app
+    configs:
+      - app-config
+      - source: devpod-authorized-keys
+        target: /root/.ssh/authorized_keys2
+configs:
+  app-config:
+    file: ./app.conf
+`,
+			wantConfigs: 2,
+		},
+		{name: "not a mapping", composeFile: "- a\n- b\n", wantErr: true},
+		{name: "invalid YAML", composeFile: "services: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := adoptComposeFile(tt.composeFile, "app", marker, publicKey)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("adoptComposeFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			parsed, err := parseWorkspaceMarker(got)
+			if err != nil || parsed == nil || *parsed != marker {
+				t.Errorf("marker of the adopted file = %+v, %v, want %+v", parsed, err, marker)
+			}
+
+			var document struct {
+				Services map[string]struct {
+					Image   string        `yaml:"image"`
+					Configs []interface{} `yaml:"configs"`
+				} `yaml:"services"`
+				Configs map[string]map[string]string `yaml:"configs"`
+			}
+			if err := yaml.Unmarshal([]byte(got), &document); err != nil {
+				t.Fatalf("adopted file is not valid YAML: %v\n%s", err, got)
+			}
+			if document.Services["app"].Image != "my/app" {
+				t.Errorf("adopted file lost the service image:\n%s", got)
+			}
+			if len(document.Services["app"].Configs) != tt.wantConfigs {
+				t.Errorf("service has %d configs, want %d:\n%s", len(document.Services["app"].Configs), tt.wantConfigs, got)
+			}
+			if document.Configs[adoptKeysConfig]["content"] != publicKey {
+				t.Errorf("config %s = %v, want the public key", adoptKeysConfig, document.Configs[adoptKeysConfig])
+			}
+			if strings.Contains(tt.composeFile, "# keep this comment") && !strings.Contains(got, "# keep this comment") {
+				t.Errorf("adopted file lost the comments:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestComposeInProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		projectID string
+		compose   dokploy.Compose
+		want      bool
+	}{
+		{name: "same project", projectID: "p1", compose: dokploy.Compose{ComposeID: "c1", ProjectID: "p1"}, want: true},
+		{name: "other project", projectID: "p1", compose: dokploy.Compose{ComposeID: "c1", ProjectID: "p2"}},
+		{name: "project does not exist", compose: dokploy.Compose{ComposeID: "c1", ProjectID: "p1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := composeInProject(nil, tt.projectID, &tt.compose)
+			if err != nil || got != tt.want {
+				t.Errorf("composeInProject() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/templates"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildImageTag       string
+	buildImageBaseImage string
+	buildImageOutputDir string
+	buildImagePush      bool
+)
+
+// rootlessPackages are baked into prebuilt images so rootless isolation can skip installation too
+var rootlessPackages = []string{"uidmap", "iptables"}
+
+// buildImageCmd represents the build-image command
+var buildImageCmd = &cobra.Command{
+	Use:   "build-image",
+	Short: "Build a prebuilt workspace image with SSH and tools installed",
+	Long: `Render a Dockerfile that bakes the SSH server, sudo and the setup script into the
+workspace base image, and build (and optionally push) it when a local Docker daemon is available.
+
+Use the resulting image with DOKPLOY_WORKSPACE_IMAGE so that workspaces skip package
+installation on every start.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBuildImage()
+	},
+}
+
+func init() {
+	buildImageCmd.Flags().StringVarP(&buildImageTag, "tag", "t", "", "image tag to build, e.g. registry.example.com/devpod/workspace:latest")
+	buildImageCmd.Flags().StringVar(&buildImageBaseImage, "base-image", options.DefaultWorkspaceImage, "Docker-in-Docker base image")
+	buildImageCmd.Flags().StringVarP(&buildImageOutputDir, "output", "o", "", "write the build context to this directory instead of a temporary one")
+	buildImageCmd.Flags().BoolVar(&buildImagePush, "push", false, "push the image after building")
+	rootCmd.AddCommand(buildImageCmd)
+}
+
+func runBuildImage() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	if buildImageTag == "" && buildImageOutputDir == "" {
+		return fmt.Errorf("either --tag (to build) or --output (to only render the build context) is required")
+	}
+
+	// Prepare the build context
+	contextDir := buildImageOutputDir
+	if contextDir == "" {
+		tempDir, err := os.MkdirTemp("", "dokploy-workspace-image-")
+		if err != nil {
+			return fmt.Errorf("failed to create build context directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+		contextDir = tempDir
+	} else if err := os.MkdirAll(contextDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte(renderDockerfile(buildImageBaseImage)), 0644); err != nil {
+		return fmt.Errorf("failed to write Dockerfile: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(contextDir, "setup-root.sh"), []byte(renderSetupScript()), 0755); err != nil {
+		return fmt.Errorf("failed to write setup script: %w", err)
+	}
+
+	logger.Infof("✓ Build context rendered in %s (base image: %s)", contextDir, buildImageBaseImage)
+
+	if buildImageTag == "" {
+		logger.Infof("Build it with: docker build -t <tag> %s", contextDir)
+		return nil
+	}
+
+	// Build only when a local Docker daemon is reachable
+	if err := exec.Command("docker", "info").Run(); err != nil {
+		if buildImageOutputDir == "" {
+			return fmt.Errorf("no local Docker daemon available (%v); use --output to keep the build context and build it elsewhere", err)
+		}
+		logger.Warnf("No local Docker daemon available (%v)", err)
+		logger.Infof("Build it elsewhere with: docker build -t %s %s", buildImageTag, contextDir)
+		return nil
+	}
+
+	logger.Infof("Building image %s...", buildImageTag)
+	if err := runDocker("build", "-t", buildImageTag, contextDir); err != nil {
+		return fmt.Errorf("failed to build image: %w", err)
+	}
+	logger.Infof("✓ Image %s built", buildImageTag)
+
+	if buildImagePush {
+		logger.Infof("Pushing image %s...", buildImageTag)
+		if err := runDocker("push", buildImageTag); err != nil {
+			return fmt.Errorf("failed to push image: %w", err)
+		}
+		logger.Infof("✓ Image %s pushed", buildImageTag)
+	}
+
+	logger.Infof("Use it with: devpod provider set-options <provider> DOKPLOY_WORKSPACE_IMAGE=%s", buildImageTag)
+	return nil
+}
+
+// renderDockerfile renders the Dockerfile template for a prebuilt workspace image
+func renderDockerfile(baseImage string) string {
+	packages := append(append([]string{}, workspacePackages...), rootlessPackages...)
+
+	dockerfile := templates.DockerfileTemplate
+	dockerfile = strings.ReplaceAll(dockerfile, "__BASE_IMAGE_PLACEHOLDER__", baseImage)
+	dockerfile = strings.ReplaceAll(dockerfile, "__PACKAGES_PLACEHOLDER__", strings.Join(packages, " "))
+	return dockerfile
+}
+
+// runDocker runs a docker CLI command with output forwarded to stderr
+func runDocker(args ...string) error {
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/templates"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// cacheComposeName is the Dokploy compose service holding the shared image cache
+	cacheComposeName = "devpod-shared-cache"
+
+	// cacheServiceName is the registry service name, reachable on dokploy-network
+	cacheServiceName = "devpod-cache-registry"
+
+	// cacheVolumeName is the node-level volume backing the shared image cache
+	cacheVolumeName = "devpod-shared-cache"
+
+	// cacheRegistryPort is the port the cache registry listens on
+	cacheRegistryPort = 5000
+)
+
+// cacheMirrorURL is the registry mirror the inner Docker daemon uses when the shared cache is enabled
+var cacheMirrorURL = fmt.Sprintf("http://%s:%d", cacheServiceName, cacheRegistryPort)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the shared workspace image cache",
+	Long: `Manage the shared image cache that workspaces use when DOKPLOY_SHARED_CACHE is enabled.
+
+The cache is a pull-through registry deployed once per Dokploy node, in the project of the first
+workspace that enables it, and shared by the workspaces of every project; the inner Docker
+daemon of every workspace uses it as a registry mirror.`,
+}
+
+// cachePruneCmd represents the cache prune command
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove the shared image cache and its volume",
+	Long: `Remove the shared cache registry of the Dokploy node together with its volume, whichever
+project it was deployed in. Running workspaces of every project fall back to pulling from Docker
+Hub, and the next workspace creation deploys an empty cache again.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCachePrune()
+	},
+}
+
+func init() {
+	cacheCmd.AddCommand(cachePruneCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func runCachePrune() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	client := dokploy.NewClient(opts, logger)
+
+	caches, err := findSharedCaches(client)
+	if err != nil {
+		return err
+	}
+	if len(caches) == 0 {
+		logger.Info("No shared image cache found, nothing to prune")
+		return nil
+	}
+
+	// Duplicates left by concurrent creates share the volume; remove them first, so that the
+	// volume is no longer in use when the cache itself is removed
+	for i := len(caches) - 1; i >= 0; i-- {
+		compose := caches[i]
+		deleteVolumes := i == 0
+		if deleteVolumes {
+			logger.Infof("Removing shared image cache %s and volume %s...", compose.ComposeID, cacheVolumeName)
+		} else {
+			logger.Infof("Removing duplicate shared image cache %s...", compose.ComposeID)
+		}
+		if err := client.DeleteCompose(compose.ComposeID, deleteVolumes); err != nil && !errors.Is(err, dokploy.ErrNotFound) {
+			return fmt.Errorf("failed to remove shared image cache: %w", err)
+		}
+	}
+
+	logger.Info("✓ Shared image cache pruned; it is redeployed empty on the next workspace creation")
+	return nil
+}
+
+// ensureSharedCache makes sure the shared cache registry of the Dokploy node is deployed. A cache
+// deployed from another project is reused; a missing one is created in projectID.
+// Workspaces do not wait for it: the inner Docker daemon falls back to Docker Hub while
+// the mirror is unreachable.
+func ensureSharedCache(client *dokploy.Client, projectID string, logger *logrus.Logger) error {
+	caches, err := findSharedCaches(client)
+	if err != nil {
+		return err
+	}
+	if len(caches) > 0 {
+		compose := caches[0]
+		switch compose.Status {
+		case "done", "running":
+			logger.Infof("✓ Shared image cache is running (%s)", cacheComposeName)
+			return nil
+		case "deploying", "building":
+			logger.Infof("Shared image cache is being deployed (%s)", cacheComposeName)
+			return nil
+		}
+
+		logger.Infof("Shared image cache is %s, redeploying...", compose.Status)
+		if err := client.DeployCompose(dokploy.DeployComposeRequest{ComposeID: compose.ComposeID}); err != nil {
+			return fmt.Errorf("failed to redeploy shared image cache: %w", err)
+		}
+		return nil
+	}
+
+	logger.Info("Deploying shared image cache...")
+	compose, err := client.CreateCompose(dokploy.CreateComposeRequest{
+		Name:        cacheComposeName,
+		Description: "Shared image cache for DevPod workspaces, managed by the Dokploy provider",
+		ProjectID:   projectID,
+		ComposeType: "docker-compose",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create shared image cache: %w", err)
+	}
+
+	// A concurrent create in another project may have created one too; only the oldest is deployed
+	if caches, err = findSharedCaches(client); err != nil {
+		return err
+	}
+	if len(caches) > 0 && caches[0].ComposeID != compose.ComposeID {
+		logger.Infof("Shared image cache %s was created concurrently, removing %s", caches[0].ComposeID, compose.ComposeID)
+		if err := client.DeleteCompose(compose.ComposeID, false); err != nil && !errors.Is(err, dokploy.ErrNotFound) {
+			return fmt.Errorf("failed to remove duplicate shared image cache: %w", err)
+		}
+		return nil
+	}
+
+	if err := client.SaveComposeFile(dokploy.SaveComposeFileRequest{
+		ComposeID:     compose.ComposeID,
+		DockerCompose: generateCacheCompose(),
+	}); err != nil {
+		return fmt.Errorf("failed to save shared image cache compose file: %w", err)
+	}
+
+	if err := client.DeployCompose(dokploy.DeployComposeRequest{ComposeID: compose.ComposeID}); err != nil {
+		return fmt.Errorf("failed to deploy shared image cache: %w", err)
+	}
+
+	logger.Infof("✓ Shared image cache deployed (volume %s)", cacheVolumeName)
+	return nil
+}
+
+// findSharedCaches returns the shared cache compose services of every project, oldest first.
+// The registry alias and the volume are node-wide, so the first one is the cache of the node.
+func findSharedCaches(client *dokploy.Client) ([]dokploy.Compose, error) {
+	caches, err := client.FindComposes(cacheComposeName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up the shared image cache: %w", err)
+	}
+	sort.Slice(caches, func(i, j int) bool {
+		if caches[i].CreatedAt != caches[j].CreatedAt {
+			return caches[i].CreatedAt < caches[j].CreatedAt
+		}
+		return caches[i].ComposeID < caches[j].ComposeID
+	})
+	return caches, nil
+}
+
+// generateCacheCompose renders the compose file of the shared cache registry
+func generateCacheCompose() string {
+	content := templates.CacheComposeTemplate
+	content = strings.ReplaceAll(content, "__CACHE_SERVICE_PLACEHOLDER__", cacheServiceName)
+	content = strings.ReplaceAll(content, "__CACHE_VOLUME_PLACEHOLDER__", cacheVolumeName)
+	return content
+}
+
+// sharedCacheService is the allowed service through which workspaces reach the cache registry
+func sharedCacheService() allowedService {
+	return allowedService{Name: cacheServiceName, Ports: []int{cacheRegistryPort}}
+}
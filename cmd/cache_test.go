@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+)
+
+func TestEnsureSharedCache(t *testing.T) {
+	// The cache of another project, created before the workspace's project looked for one
+	otherCache := func(status string) *dokploy.Compose {
+		return &dokploy.Compose{ComposeID: "cache-other", Name: cacheComposeName, ProjectID: "project-1", Status: status, CreatedAt: "2025-01-01T00:00:00Z"}
+	}
+
+	tests := []struct {
+		name       string
+		existing   *dokploy.Compose
+		concurrent bool
+		wantPosts  []string
+		wantCaches []string
+	}{
+		{
+			name:       "no cache on the node",
+			wantPosts:  []string{"/api/compose.create", "/api/compose.update", "/api/compose.deploy"},
+			wantCaches: []string{"compose-1"},
+		},
+		{
+			name:       "cache running in another project",
+			existing:   otherCache("done"),
+			wantCaches: []string{"cache-other"},
+		},
+		{
+			name:       "cache stopped in another project",
+			existing:   otherCache("idle"),
+			wantPosts:  []string{"/api/compose.deploy"},
+			wantCaches: []string{"cache-other"},
+		},
+		{
+			name:       "cache created concurrently in another project",
+			concurrent: true,
+			wantPosts:  []string{"/api/compose.create", "/api/compose.delete"},
+			wantCaches: []string{"cache-other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeDokploy{
+				projects: []dokploy.Project{{ProjectID: "project-1", Name: "other"}, {ProjectID: "project-2", Name: "workspaces"}},
+				composes: map[string]*dokploy.Compose{},
+			}
+			if tt.existing != nil {
+				fake.composes[tt.existing.ComposeID] = tt.existing
+			}
+			if tt.concurrent {
+				fake.created = func(*dokploy.Compose) {
+					fake.composes["cache-other"] = otherCache("idle")
+				}
+			}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			opts := testOptions()
+			opts.DokployServerURL = server.URL
+			if err := ensureSharedCache(dokploy.NewClient(opts, testLogger()), "project-2", testLogger()); err != nil {
+				t.Fatalf("ensureSharedCache() error = %v", err)
+			}
+
+			if posts := fake.takePosts(); !reflect.DeepEqual(posts, tt.wantPosts) {
+				t.Errorf("ensureSharedCache() calls = %v, want %v", posts, tt.wantPosts)
+			}
+			var caches []string
+			for id, compose := range fake.composes {
+				if compose.Name == cacheComposeName {
+					caches = append(caches, id)
+				}
+			}
+			sort.Strings(caches)
+			if !reflect.DeepEqual(caches, tt.wantCaches) {
+				t.Errorf("shared caches = %v, want %v", caches, tt.wantCaches)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -207,21 +208,26 @@ func runCommand() error {
 
 	// Get full compose service details
 	logger.Debug("=== GETTING COMPOSE SERVICE DETAILS ===")
-	_, err = dokployClient.GetCompose(compose.ComposeID)
+	var sshPort string
+	fullCompose, err := dokployClient.GetCompose(compose.ComposeID)
 	if err != nil {
 		logger.Warnf("Failed to get full compose service details: %v", err)
 	} else {
 		logger.Debugf("✓ Retrieved full compose service details")
+
+		// Workspaces carry their SSH port in the x-devpod marker of the compose file
+		if marker, err := parseWorkspaceMarker(fullCompose.ComposeFile); err == nil && marker != nil && marker.SSHPort > 0 {
+			sshPort = strconv.Itoa(marker.SSHPort)
+			logger.Debugf("✓ SSH port from workspace marker: %s", sshPort)
+		}
 	}
 
-	// For Docker Compose services, we need to find the SSH port from the Docker Compose configuration
-	// Since the port was allocated during creation (in create.go), we need to discover it
+	// Older workspaces have no marker, so discover the port allocated during creation
 	logger.Debug("=== FINDING SSH PORT FOR COMPOSE SERVICE ===")
-	var sshPort string
 	maxRetries := 10
 	retryDelay := 2 * time.Second
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
+	for attempt := 1; sshPort == "" && attempt <= maxRetries; attempt++ {
 		logger.Debugf("Attempt %d/%d: Looking for SSH port for compose service", attempt, maxRetries)
 		
 		// Extract hostname from ServerURL
@@ -237,8 +243,8 @@ func runCommand() error {
 			hostname = hostname[:colonIdx]
 		}
 		
-		// Check ports in the range we use for SSH (2222-2250)
-		for port := 2222; port <= 2250; port++ {
+		// Check ports in the range we use for SSH
+		for port := sshPortRangeStart; port <= sshPortRangeEnd; port++ {
 			// Test if this port is accessible and is SSH
 			if isSSHPortActive(hostname, port, logger) {
 				sshPort = fmt.Sprintf("%d", port)
@@ -259,7 +265,7 @@ func runCommand() error {
 
 	if sshPort == "" {
 		logger.Errorf("SSH port not found for compose service %s", machineID)
-		logger.Error("No accessible SSH port found in range 2222-2250")
+		logger.Errorf("No accessible SSH port found in range %d-%d", sshPortRangeStart, sshPortRangeEnd)
 		logger.Error("This may indicate the compose service is still starting up or failed to deploy")
 		return fmt.Errorf("SSH port not found for compose service %s", machineID)
 	}
@@ -292,6 +298,13 @@ func runCommand() error {
 		hostname = strings.TrimPrefix(hostname, "http://")
 	}
 	
+	// Migrated workspaces may publish SSH on another host
+	if fullCompose != nil {
+		if marker, err := parseWorkspaceMarker(fullCompose.ComposeFile); err == nil && marker != nil && marker.SSHHost != "" {
+			hostname = marker.SSHHost
+		}
+	}
+
 	sshAddress := hostname + ":" + sshPort
 	logger.Debugf("SSH address: %s", sshAddress)
 	logger.Debugf("SSH user: root")
@@ -322,7 +335,7 @@ func runCommand() error {
 
 // isSSHPortActive tests if a port is accessible and responds as an SSH service
 func isSSHPortActive(host string, port int, logger *logrus.Logger) bool {
-	testAddress := fmt.Sprintf("%s:%d", host, port)
+	testAddress := net.JoinHostPort(host, strconv.Itoa(port))
 	
 	// First check if the port is accessible
 	conn, err := net.DialTimeout("tcp", testAddress, 2*time.Second)
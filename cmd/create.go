@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -22,13 +24,16 @@ var createCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new Dokploy workspace using Docker Compose",
 	Long: `Create a new development workspace in Dokploy using Docker Compose with automatic SSH setup,
-privileged mode support, and Docker-in-Docker capabilities.`,
+privileged, sysbox or rootless isolation, and Docker-in-Docker capabilities.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runCreate()
 	},
 }
 
+var createDryRun bool
+
 func init() {
+	createCmd.Flags().BoolVar(&createDryRun, "dry-run", false, "print the compose file, SSH port and Dokploy API calls without changing anything")
 	rootCmd.AddCommand(createCmd)
 }
 
@@ -81,9 +86,26 @@ func runCreate() error {
 		return fmt.Errorf("DEVPOD_MACHINE_ID is required for workspace creation")
 	}
 
+	// Resolve everything the compose file is rendered from before touching Dokploy
+	params, err := buildComposeParams(opts, logger)
+	if err != nil {
+		return err
+	}
+	machineType := params.MachineType
+
 	// Create Dokploy client
 	client := dokploy.NewClient(opts, logger)
 
+	// Only read from Dokploy and print what would be done
+	if createDryRun {
+		plan, err := buildCreatePlan(client, opts, params, machineID, logger)
+		if err != nil {
+			return err
+		}
+		plan.Write(os.Stdout)
+		return nil
+	}
+
 	// Check if project exists, create if it doesn't
 	logger.Infof("Checking if project '%s' exists...", opts.DokployProjectName)
 	projects, err := client.GetAllProjects()
@@ -116,6 +138,29 @@ func runCreate() error {
 		logger.Infof("✓ Project '%s' already exists with ID: %s", opts.DokployProjectName, projectID)
 	}
 
+	// Adopt the workspace left behind by an earlier, interrupted create instead of duplicating it
+	existing, existingMarker, err := findExistingWorkspace(client, projectID, machineID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		logger.Infof("Found existing workspace compose service %s (status: %s), reconciling it", existing.ComposeID, existing.Status)
+	}
+
+	// Make sure the Dokploy node supports the requested isolation mode
+	if needsIsolationCheck(opts, existingMarker) {
+		if err := verifyIsolationSupport(client, projectID, opts, logger); err != nil {
+			return err
+		}
+	}
+
+	// Deploy the shared image cache the inner Docker daemon uses as a registry mirror
+	if opts.SharedCache {
+		if err := ensureSharedCache(client, projectID, logger); err != nil {
+			logger.Warnf("Shared image cache unavailable, images are pulled directly: %v", err)
+		}
+	}
+
 	// Get SSH public key from DevPod for injection into container
 	logger.Info("Getting SSH public key from DevPod...")
 	machineFolder := os.Getenv("MACHINE_FOLDER")
@@ -125,22 +170,9 @@ func runCreate() error {
 
 	logger.Debugf("Machine folder: %s", machineFolder)
 
-	publicKey, err := ssh.GetPublicKeyBase(machineFolder)
+	publicKey, err := loadMachinePublicKey(machineFolder, logger)
 	if err != nil {
-		return fmt.Errorf("failed to get SSH public key: %w", err)
-	}
-
-	logger.Debugf("Retrieved SSH public key (full): %s", publicKey)
-	
-	// Decode the base64 encoded SSH key if needed
-	if !strings.HasPrefix(publicKey, "ssh-") {
-		logger.Info("Decoding base64 encoded SSH key from DevPod...")
-		decodedKey, err := base64.StdEncoding.DecodeString(publicKey)
-		if err != nil {
-			return fmt.Errorf("failed to decode base64 SSH key: %w", err)
-		}
-		publicKey = string(decodedKey)
-		logger.Infof("✓ SSH key decoded successfully")
+		return err
 	}
 	
 	logger.Info("✓ SSH public key retrieved from DevPod")
@@ -152,118 +184,98 @@ func runCreate() error {
 	}
 	sshHost := strings.Split(parsedURL.Host, ":")[0]
 
-	// Find an available SSH port
-	var sshHostPort int
-	logger.Info("Finding available SSH port (range 2222-2250)...")
-
-	// Check existing port usage
-	allProjects, err := client.GetAllProjects()
-	if err != nil {
-		logger.Warnf("Failed to get projects for port conflict check: %v", err)
-	}
-
-	usedPorts := make(map[int]bool)
-	if allProjects != nil {
-		for _, project := range allProjects {
-			for _, compose := range project.Composes {
-				logger.Debugf("Checking compose service %s for port usage", compose.Name)
-				// Note: We'll need to add compose port checking in the client
-			}
+	// Reuse the SSH port of an existing workspace, otherwise find an available one
+	sshHostPort := reusableSSHPort(existingMarker)
+	if sshHostPort > 0 {
+		logger.Infof("✓ Reusing SSH port %d of the existing workspace", sshHostPort)
+	} else {
+		sshHostPort, err = findAvailableSSHPort(client, sshHost, logger)
+		if err != nil {
+			return err
 		}
 	}
 
-	// Find available port
-	portFound := false
-	for port := 2222; port <= 2250; port++ {
-		if usedPorts[port] {
-			continue
-		}
-
-		// Test network availability
-		testAddress := fmt.Sprintf("%s:%d", sshHost, port)
-		conn, err := net.DialTimeout("tcp", testAddress, 3*time.Second)
-		if err == nil {
-			conn.Close()
-			usedPorts[port] = true
-			continue
+	// Create the Docker Compose service in Dokploy, or reuse the existing one. The service is
+	// created before the compose file is rendered because the file refers to its ID.
+	compose := existing
+	if compose == nil {
+		logger.Info("Creating Docker Compose service in Dokploy...")
+
+		compose, err = client.CreateCompose(dokploy.CreateComposeRequest{
+			Name:        machineID,
+			Description: fmt.Sprintf("DevPod workspace created on %s via Docker Compose", time.Now().Format(time.RFC3339)),
+			ProjectID:   projectID,
+			ComposeType: "docker-compose", // Use docker-compose instead of stack for full feature support
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create Docker Compose service: %w", err)
 		}
-		
-		sshHostPort = port
-		portFound = true
-		logger.Infof("✓ Selected SSH port: %d", port)
-		break
-	}
 
-	if !portFound {
-		return fmt.Errorf("no available ports in range 2222-2250")
+		logger.Infof("✓ Docker Compose service created with ID: %s", compose.ComposeID)
 	}
 
-	// Create docker-compose.yml content with privileged mode
-	logger.Info("Creating Docker Compose configuration with privileged mode...")
+	// Create docker-compose.yml content for the selected isolation mode
+	logger.Infof("Creating Docker Compose configuration (%s isolation)...", opts.Isolation)
 	
-	dockerComposeContent, err := generateDockerCompose(sshHostPort, publicKey, logger)
+	logger.Infof("Workspace volumes: %s, %s (reattached if retained from a previous workspace)",
+		dockerVolumeName(machineID), workspaceVolumeName(machineID))
+
+	params.MachineID = machineID
+	params.SSHPort = sshHostPort
+	params.SSHPublicKey = publicKey
+	params.ComposeID = compose.ComposeID
+	dockerComposeContent, err := generateDockerCompose(params, logger)
 	if err != nil {
 		return fmt.Errorf("failed to generate Docker Compose configuration: %w", err)
 	}
 
-	logger.Info("✓ Docker Compose configuration created with full privileged mode support")
-
-	// Create Docker Compose service in Dokploy
-	logger.Info("Creating Docker Compose service in Dokploy...")
-	
-	compose, err := client.CreateCompose(dokploy.CreateComposeRequest{
-		Name:        machineID,
-		Description: fmt.Sprintf("DevPod workspace created on %s via Docker Compose", time.Now().Format(time.RFC3339)),
-		ProjectID:   projectID,
-		ComposeType: "docker-compose", // Use docker-compose instead of stack for full feature support
-	})
-	if err != nil {
-		return fmt.Errorf("failed to create Docker Compose service: %w", err)
-	}
-
-	logger.Infof("✓ Docker Compose service created with ID: %s", compose.ComposeID)
+	logger.Infof("✓ Docker Compose configuration created with %s isolation", opts.Isolation)
+
+	// Set the docker-compose.yml content, unless the existing service already has it
+	composeEnv := inactivityComposeEnv(opts)
+	composeChanged := composeNeedsUpdate(existing, dockerComposeContent, composeEnv)
+	if composeChanged {
+		logger.Info("Uploading Docker Compose configuration...")
+		err = client.SaveComposeFile(dokploy.SaveComposeFileRequest{
+			ComposeID:     compose.ComposeID,
+			DockerCompose: dockerComposeContent,
+			Env:           composeEnv,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save Docker Compose file: %w", err)
+		}
 
-	// Set the docker-compose.yml content
-	logger.Info("Uploading Docker Compose configuration...")
-	err = client.SaveComposeFile(dokploy.SaveComposeFileRequest{
-		ComposeID:     compose.ComposeID,
-		DockerCompose: dockerComposeContent,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to save Docker Compose file: %w", err)
+		logger.Info("✓ Docker Compose file uploaded successfully")
+	} else {
+		logger.Info("✓ Docker Compose configuration is up to date")
 	}
 
-	logger.Info("✓ Docker Compose file uploaded successfully")
-
 	// Deploy the Docker Compose service
 	logger.Info("Deploying Docker Compose service...")
 	logger.Info("")
 	logger.Info("🐳 Enhanced Docker Compose Deployment:")
-	logger.Info("   • Privileged mode: ENABLED (full Docker-in-Docker support)")
-	logger.Info("   • SSH port mapping: External port %d → Container port 22", sshHostPort)
-	logger.Info("   • Base image: cruizba/ubuntu-dind:latest")
+	logger.Infof("   • Isolation: %s", isolationDescription(opts.Isolation))
+	logger.Infof("   • SSH port mapping: External port %d → Container port 22", sshHostPort)
+	logger.Infof("   • Machine type: %s (cpus: %s, memory: %s, pids: %d)", opts.MachineType, machineType.CPUs, machineType.Memory, machineType.PidsLimit)
+	logger.Infof("   • Base image: %s", opts.WorkspaceImage)
 	logger.Info("   • User setup: devpod user with sudo and docker group access")
 	logger.Info("   • SSH authentication: Both key-based and password authentication")
 	logger.Info("   • Docker daemon: Full dockerd with overlay2 storage driver")
 	logger.Info("")
-	logger.Info("⏳ Deployment Timeline:")
-	logger.Info("   • Stage 1: Docker daemon startup (30-60 seconds)")
-	logger.Info("   • Stage 2: Package installation (30-60 seconds)")
-	logger.Info("   • Stage 3: User creation (10-20 seconds)")
-	logger.Info("   • Stage 4: SSH key setup (10-20 seconds)")
-	logger.Info("   • Stage 5: SSH daemon config (10-20 seconds)")
-	logger.Info("   • Stage 6: SSH service start (10-20 seconds)")
-	logger.Info("   • Total estimated time: 2-4 minutes")
-	logger.Info("")
 
-	err = client.DeployCompose(dokploy.DeployComposeRequest{
-		ComposeID: compose.ComposeID,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to deploy Docker Compose service: %w", err)
-	}
+	// An adopted workspace that is already deployed with the current configuration is left running
+	if !composeNeedsDeploy(existing, composeChanged) {
+		logger.Info("✓ Existing deployment is current, skipping redeploy")
+	} else {
+		err = client.DeployCompose(dokploy.DeployComposeRequest{
+			ComposeID: compose.ComposeID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to deploy Docker Compose service: %w", err)
+		}
 
-	logger.Info("✓ Docker Compose deployment started")
+		logger.Info("✓ Docker Compose deployment started")
+	}
 
 	// Wait for deployment to complete
 	logger.Info("Waiting for Docker Compose deployment to complete...")
@@ -287,27 +299,22 @@ func runCreate() error {
 			break
 		}
 
-		// Provide stage-specific feedback
-		var stageInfo string
-		if elapsedTime < 60*time.Second {
-			stageInfo = "(likely Stage 1-2: Docker daemon + package installation)"
-		} else if elapsedTime < 120*time.Second {
-			stageInfo = "(likely Stage 3-6: User setup + SSH configuration)"
-		} else {
-			stageInfo = "(likely finalizing SSH service)"
-		}
-
-		logger.Infof("   Deployment status: %s - %v elapsed %s (attempt %d/60)", currentCompose.Status, elapsedTime, stageInfo, i)
+		logger.Infof("   Deployment status: %s - %v elapsed (attempt %d/60)", currentCompose.Status, elapsedTime, i)
 		time.Sleep(5 * time.Second)
 	}
 
-	// Additional wait for SSH service to be fully ready
-	logger.Info("Waiting for SSH service to be fully accessible...")
-	time.Sleep(15 * time.Second)
+	// Follow the setup progress reported by the workspace container
+	logger.Info("Waiting for workspace setup to complete...")
+	if err := waitForWorkspaceReady(client, compose.ComposeID, opts, setupWaitTimeout, logger); err != nil {
+		if !errors.Is(err, errWaitTimeout) {
+			return err
+		}
+		logger.Warnf("%v; DevPod will keep polling the workspace status", err)
+	}
 
 	logger.Info("")
 	logger.Info("✅ Dokploy workspace created successfully via Docker Compose!")
-	logger.Info("🎉 Privileged Docker-in-Docker workspace deployment completed!")
+	logger.Info("🎉 Docker-in-Docker workspace deployment completed!")
 	logger.Info("")
 	logger.Info("Workspace Details:")
 	logger.Infof("- Compose ID: %s", compose.ComposeID)
@@ -315,13 +322,14 @@ func runCreate() error {
 	logger.Infof("- SSH Port: %d", sshHostPort)
 	logger.Info("- SSH User: devpod")
 	logger.Info("- SSH Auth: Key + password authentication")
-	logger.Info("- Privileged Mode: ENABLED")
-	logger.Info("- Base Image: cruizba/ubuntu-dind:latest")
+	logger.Infof("- Machine Type: %s", opts.MachineType)
+	logger.Infof("- Isolation: %s", opts.Isolation)
+	logger.Infof("- Base Image: %s", opts.WorkspaceImage)
 	logger.Info("- Docker Daemon: Full Docker-in-Docker with overlay2")
 	logger.Infof("- Dokploy Dashboard: %s", opts.DokployServerURL)
 	logger.Info("")
 	logger.Info("🐳 Container Capabilities:")
-	logger.Info("- Full Docker daemon: Available with privileged mode")
+	logger.Infof("- Full Docker daemon: Available with %s isolation", opts.Isolation)
 	logger.Info("- Docker commands: docker build, run, compose, etc.")
 	logger.Info("- SSH access: Ready for DevPod connection")
 	logger.Info("- Development environment: Full containerized development")
@@ -343,18 +351,138 @@ func runCreate() error {
 	return nil
 }
 
+// composeParams holds the values rendered into the docker-compose.yml template
+type composeParams struct {
+	MachineID       string
+	SSHHost         string
+	SSHPort         int
+	SSHPublicKey    string
+	MachineType     options.MachineTypeSpec
+	MachineTypeName string
+	Isolation       string
+	Image           string
+	Sidecars        *sidecarSpec
+	DaemonConfig    *dockerDaemonConfig
+	Settings        *workspaceSettings
+
+	PostSetupScript string
+	DotfilesURL     string
+
+	ComposeID         string
+	DokployURL        string
+	InactivityTimeout time.Duration
+
+	JoinDokployNetwork bool
+	AllowedServices    []allowedService
+}
+
+// buildComposeParams resolves the options the workspace compose file is rendered from. The
+// machine-specific fields (machine ID, SSH port and key, compose ID) are left to the caller.
+func buildComposeParams(opts *options.Options, logger *logrus.Logger) (composeParams, error) {
+	// Parse sidecar services declared alongside the workspace
+	sidecarDefinition, err := opts.SidecarDefinition()
+	if err != nil {
+		return composeParams{}, fmt.Errorf("failed to load sidecar services: %w", err)
+	}
+	sidecars, err := parseSidecars(sidecarDefinition)
+	if err != nil {
+		return composeParams{}, fmt.Errorf("invalid DOKPLOY_SIDECARS: %w", err)
+	}
+	if names := sidecarNames(sidecars); len(names) > 0 {
+		logger.Infof("Sidecar services: %s (reachable from the workspace by service name)", strings.Join(names, ", "))
+	}
+
+	// Workspaces get a private network; dokploy-network is only reachable when asked for
+	allowedServices, err := parseAllowedServices(opts.AllowedServices)
+	if err != nil {
+		return composeParams{}, fmt.Errorf("invalid DOKPLOY_ALLOWED_SERVICES: %w", err)
+	}
+	if opts.JoinDokployNetwork {
+		logger.Info("Workspace joins dokploy-network (DOKPLOY_JOIN_DOKPLOY_NETWORK=true)")
+	}
+	if opts.SharedCache {
+		allowedServices = append(allowedServices, sharedCacheService())
+	}
+	for _, service := range allowedServices {
+		logger.Infof("Workspace may reach Dokploy service %s on ports %v", service.Name, service.Ports)
+	}
+
+	// User hooks run inside the workspace after SSH is configured
+	postSetupScript, err := opts.PostSetupScript()
+	if err != nil {
+		return composeParams{}, fmt.Errorf("failed to load post-setup script: %w", err)
+	}
+	if postSetupScript != "" {
+		logger.Info("Post-setup script will run after the workspace setup")
+	}
+	if opts.DotfilesURL != "" {
+		logger.Infof("Dotfiles from %s will be installed after the workspace setup", opts.DotfilesURL)
+	}
+
+	// Idle workspaces stop themselves through the Dokploy API with a dedicated token
+	if opts.InactivityTimeout > 0 {
+		if opts.InactivityAPIToken == "" {
+			return composeParams{}, fmt.Errorf("DOKPLOY_INACTIVITY_TIMEOUT requires DOKPLOY_INACTIVITY_API_TOKEN, an API token of a Dokploy user " +
+				"restricted to the workspace project; the workspace uses it to stop itself")
+		}
+		logger.Infof("Workspace stops itself after %v without SSH sessions", opts.InactivityTimeout)
+	}
+
+	// Render the inner Docker daemon configuration
+	daemonConfig, err := buildDockerDaemonConfig(opts)
+	if err != nil {
+		return composeParams{}, fmt.Errorf("invalid inner Docker daemon options: %w", err)
+	}
+	if daemonConfig != nil {
+		logger.Info("Inner Docker daemon will be configured through /etc/docker/daemon.json")
+	}
+
+	// The settings are recorded in the marker for upgrade, resize and migrate
+	settings, err := settingsFromOptions(opts)
+	if err != nil {
+		return composeParams{}, err
+	}
+
+	// Resolve resource limits for the requested machine type
+	machineType, err := opts.ResolveMachineType()
+	if err != nil {
+		return composeParams{}, fmt.Errorf("failed to resolve machine type: %w", err)
+	}
+	logger.Infof("Using machine type '%s' (cpus: %s, memory: %s)", opts.MachineType, machineType.CPUs, machineType.Memory)
+
+	return composeParams{
+		MachineType:     machineType,
+		MachineTypeName: opts.MachineType,
+		Isolation:       opts.Isolation,
+		Image:           opts.WorkspaceImage,
+		Sidecars:        sidecars,
+		DaemonConfig:    daemonConfig,
+		Settings:        settings,
+
+		PostSetupScript: postSetupScript,
+		DotfilesURL:     opts.DotfilesURL,
+
+		DokployURL:        opts.DokployServerURL,
+		InactivityTimeout: opts.InactivityTimeout,
+
+		JoinDokployNetwork: opts.JoinDokployNetwork,
+		AllowedServices:    allowedServices,
+	}, nil
+}
+
 // generateDockerCompose generates the docker-compose.yml content from embedded templates
-func generateDockerCompose(sshPort int, sshPublicKey string, logger *logrus.Logger) (string, error) {
+func generateDockerCompose(params composeParams, logger *logrus.Logger) (string, error) {
 	logger.Debugf("=== GENERATING DOCKER COMPOSE ===")
-	logger.Debugf("SSH Port: %d", sshPort)
-	logger.Debugf("SSH Key length: %d", len(sshPublicKey))
+	logger.Debugf("SSH Port: %d", params.SSHPort)
+	logger.Debugf("SSH Key length: %d", len(params.SSHPublicKey))
+	logger.Debugf("Resource limits: %+v", params.MachineType)
 	
 	// Use embedded template constants
 	logger.Debugf("Docker compose template loaded (%d bytes)", len(templates.DockerComposeTemplate))
 	logger.Debugf("Setup script template loaded (%d bytes)", len(templates.SetupScriptTemplate))
 
 	// Encode the setup script as base64 to avoid quoting/escaping issues
-	encodedScript := base64.StdEncoding.EncodeToString([]byte(templates.SetupScriptTemplate))
+	encodedScript := base64.StdEncoding.EncodeToString([]byte(renderSetupScript()))
 
 	// Create a command that decodes and executes the script
 	setupCommand := fmt.Sprintf("echo '%s' | base64 -d | bash", encodedScript)
@@ -363,7 +491,7 @@ func generateDockerCompose(sshPort int, sshPublicKey string, logger *logrus.Logg
 	logger.Debugf("Setup command: %s", setupCommand)
 	
 	// Prepare SSH key - trim whitespace and escape for YAML
-	sshPublicKey = strings.TrimSpace(sshPublicKey)
+	sshPublicKey := strings.TrimSpace(params.SSHPublicKey)
 	logger.Debugf("SSH public key to inject: %s", sshPublicKey)
 	escapedSSHKey := strings.ReplaceAll(sshPublicKey, `"`, `\"`)
 
@@ -373,9 +501,68 @@ func generateDockerCompose(sshPort int, sshPublicKey string, logger *logrus.Logg
 	logger.Debugf("Before replacement - contains SSH_KEY placeholder: %v", strings.Contains(dockerCompose, "__SSH_PUBLIC_KEY_PLACEHOLDER__"))
 	logger.Debugf("Before replacement - contains SCRIPT placeholder: %v", strings.Contains(dockerCompose, "__SETUP_SCRIPT_PLACEHOLDER__"))
 	
-	dockerCompose = strings.ReplaceAll(dockerCompose, "__SSH_PORT_PLACEHOLDER__", fmt.Sprintf("%d", sshPort))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__SSH_PORT_PLACEHOLDER__", fmt.Sprintf("%d", params.SSHPort))
+	var sshHostLines []string
+	if params.SSHHost != "" {
+		sshHostLines = []string{"ssh-host: " + params.SSHHost}
+	}
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__SSH_HOST_PLACEHOLDER__", sshHostLines)
+	settingsLines, err := settingsLines(params.Settings)
+	if err != nil {
+		return "", err
+	}
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__SETTINGS_PLACEHOLDER__", settingsLines)
 	dockerCompose = strings.ReplaceAll(dockerCompose, "__SSH_PUBLIC_KEY_PLACEHOLDER__", escapedSSHKey)
 	dockerCompose = strings.ReplaceAll(dockerCompose, "__SETUP_SCRIPT_PLACEHOLDER__", setupCommand)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__IMAGE_PLACEHOLDER__", params.Image)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__MANAGED_BY_PLACEHOLDER__", providerMarker)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__MACHINE_ID_PLACEHOLDER__", params.MachineID)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__MACHINE_TYPE_PLACEHOLDER__", params.MachineTypeName)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__TEMPLATE_VERSION_PLACEHOLDER__", strconv.Itoa(templateVersion))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__CPUS_PLACEHOLDER__", params.MachineType.CPUs)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__MEM_LIMIT_PLACEHOLDER__", params.MachineType.Memory)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__MEMSWAP_LIMIT_PLACEHOLDER__", params.MachineType.MemorySwap)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__PIDS_LIMIT_PLACEHOLDER__", strconv.Itoa(params.MachineType.PidsLimit))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__SHM_SIZE_PLACEHOLDER__", params.MachineType.ShmSize)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__DOCKER_VOLUME_PLACEHOLDER__", dockerVolumeName(params.MachineID))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__WORKSPACE_VOLUME_PLACEHOLDER__", workspaceVolumeName(params.MachineID))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__SSH_HOST_KEYS_VOLUME_PLACEHOLDER__", sshHostKeysVolumeName(params.MachineID))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__PRIVILEGED_PLACEHOLDER__", strconv.FormatBool(params.Isolation == options.IsolationPrivileged))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__ISOLATION_MODE_PLACEHOLDER__", params.Isolation)
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__ISOLATION_PLACEHOLDER__", isolationComposeLines(params.Isolation))
+
+	daemonConfig, err := encodeDockerDaemonConfig(params.DaemonConfig)
+	if err != nil {
+		return "", err
+	}
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__DOCKER_DAEMON_CONFIG_PLACEHOLDER__", daemonConfig)
+
+	var encodedPostSetupScript string
+	if params.PostSetupScript != "" {
+		encodedPostSetupScript = base64.StdEncoding.EncodeToString([]byte(params.PostSetupScript))
+	}
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__POST_SETUP_SCRIPT_PLACEHOLDER__", encodedPostSetupScript)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__DOTFILES_URL_PLACEHOLDER__", params.DotfilesURL)
+
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__INACTIVITY_TIMEOUT_PLACEHOLDER__", strconv.Itoa(int(params.InactivityTimeout.Seconds())))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__DOKPLOY_URL_PLACEHOLDER__", strings.TrimRight(params.DokployURL, "/"))
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__COMPOSE_ID_PLACEHOLDER__", params.ComposeID)
+
+	// Sidecars and allowed-service proxies share the private per-workspace network
+	extraServices, err := extraServiceLines(params.Sidecars, allowedServiceProxies(params.AllowedServices))
+	if err != nil {
+		return "", err
+	}
+	sidecarVolumes, err := sidecarVolumeLines(params.Sidecars)
+	if err != nil {
+		return "", err
+	}
+	workspaceNetworks, networks := networkComposeLines(params.JoinDokployNetwork, params.AllowedServices)
+	dockerCompose = strings.ReplaceAll(dockerCompose, "__NETWORK_PLACEHOLDER__", workspaceNetworkName(params.MachineID))
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__WORKSPACE_NETWORKS_PLACEHOLDER__", workspaceNetworks)
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__NETWORKS_PLACEHOLDER__", networks)
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__SIDECAR_SERVICES_PLACEHOLDER__", extraServices)
+	dockerCompose = replaceLinePlaceholder(dockerCompose, "__SIDECAR_VOLUMES_PLACEHOLDER__", sidecarVolumes)
 
 	logger.Debugf("After replacement - contains SSH_PORT placeholder: %v", strings.Contains(dockerCompose, "__SSH_PORT_PLACEHOLDER__"))
 	logger.Debugf("After replacement - contains SSH_KEY placeholder: %v", strings.Contains(dockerCompose, "__SSH_PUBLIC_KEY_PLACEHOLDER__"))
@@ -384,4 +571,154 @@ func generateDockerCompose(sshPort int, sshPublicKey string, logger *logrus.Logg
 	logger.Debugf("Generated docker-compose.yml:\n%s", dockerCompose)
 	
 	return dockerCompose, nil
-} 
\ No newline at end of file
+} 
+
+// workspacePackages are the packages the setup script installs when they are missing from the image
+var workspacePackages = []string{"openssh-server", "sudo", "curl", "wget", "ca-certificates", "gnupg", "zstd"}
+
+// renderSetupScript renders the setup-root.sh template
+func renderSetupScript() string {
+	return strings.ReplaceAll(templates.SetupScriptTemplate, "__PACKAGES_PLACEHOLDER__", strings.Join(workspacePackages, " "))
+}
+
+// replaceLinePlaceholder replaces the whole line containing placeholder with the given
+// lines, keeping the placeholder's indentation. The line is dropped when lines is empty.
+func replaceLinePlaceholder(content, placeholder string, lines []string) string {
+	var result []string
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.Contains(line, placeholder) {
+			result = append(result, line)
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " "))]
+		for _, replacement := range lines {
+			result = append(result, indent+replacement)
+		}
+	}
+	return strings.Join(result, "\n")
+}
+
+// workspaceNetworkName returns the name of the private network of a machine
+func workspaceNetworkName(machineID string) string {
+	return fmt.Sprintf("devpod-%s", sanitizeVolumeName(machineID))
+}
+
+// dockerVolumeName returns the named volume holding the inner Docker storage of a machine
+func dockerVolumeName(machineID string) string {
+	return fmt.Sprintf("devpod-%s-docker", sanitizeVolumeName(machineID))
+}
+
+// workspaceVolumeName returns the named volume holding the /workspace data of a machine
+func workspaceVolumeName(machineID string) string {
+	return fmt.Sprintf("devpod-%s-workspace", sanitizeVolumeName(machineID))
+}
+
+// sshHostKeysVolumeName returns the named volume keeping the SSH host keys of a machine across container recreation
+func sshHostKeysVolumeName(machineID string) string {
+	return fmt.Sprintf("devpod-%s-ssh", sanitizeVolumeName(machineID))
+}
+
+// sanitizeVolumeName replaces characters Docker does not allow in volume names
+func sanitizeVolumeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '.' || r == '-' {
+			return r
+		}
+		return '-'
+	}, name)
+}
+
+// loadMachinePublicKey returns the SSH public key DevPod generated for a machine
+func loadMachinePublicKey(machineFolder string, logger *logrus.Logger) (string, error) {
+	publicKey, err := ssh.GetPublicKeyBase(machineFolder)
+	if err != nil {
+		return "", fmt.Errorf("failed to get SSH public key: %w", err)
+	}
+
+	logger.Debugf("Retrieved SSH public key (full): %s", publicKey)
+	
+	// Decode the base64 encoded SSH key if needed
+	if !strings.HasPrefix(publicKey, "ssh-") {
+		logger.Info("Decoding base64 encoded SSH key from DevPod...")
+		decodedKey, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode base64 SSH key: %w", err)
+		}
+		publicKey = string(decodedKey)
+		logger.Infof("✓ SSH key decoded successfully")
+	}
+	return publicKey, nil
+}
+
+// The decisions below are shared by runCreate and buildCreatePlan, so that create --dry-run and
+// render print the calls create makes.
+
+// reusableSSHPort returns the SSH port of an existing workspace, or 0 when a new one is needed
+func reusableSSHPort(existingMarker *workspaceMarker) int {
+	if existingMarker == nil {
+		return 0
+	}
+	return existingMarker.SSHPort
+}
+
+// composeNeedsUpdate reports whether the compose file and environment have to be uploaded
+func composeNeedsUpdate(existing *dokploy.Compose, composeFile, env string) bool {
+	return existing == nil || existing.ComposeFile != composeFile || existing.Env != env
+}
+
+// composeNeedsDeploy reports whether the workspace has to be deployed: after an upload, or when
+// the existing deployment is not running
+func composeNeedsDeploy(existing *dokploy.Compose, updated bool) bool {
+	return updated || existing == nil || (existing.Status != "done" && existing.Status != "running")
+}
+
+// findAvailableSSHPort returns the first port in the SSH range (sshPortRangeStart-sshPortRangeEnd) that does not answer on the Dokploy host
+func findAvailableSSHPort(client *dokploy.Client, sshHost string, logger *logrus.Logger) (int, error) {
+	logger.Infof("Finding available SSH port (range %d-%d)...", sshPortRangeStart, sshPortRangeEnd)
+
+	// Check existing port usage
+	allProjects, err := client.GetAllProjects()
+	if err != nil {
+		logger.Warnf("Failed to get projects for port conflict check: %v", err)
+	}
+
+	usedPorts := make(map[int]bool)
+	if allProjects != nil {
+		for _, project := range allProjects {
+			for _, compose := range project.Composes {
+				logger.Debugf("Checking compose service %s for port usage", compose.Name)
+				// Note: We'll need to add compose port checking in the client
+			}
+		}
+	}
+
+	// Find available port
+	for port := sshPortRangeStart; port <= sshPortRangeEnd; port++ {
+		if usedPorts[port] {
+			continue
+		}
+
+		// Test network availability
+		testAddress := net.JoinHostPort(sshHost, strconv.Itoa(port))
+		conn, err := net.DialTimeout("tcp", testAddress, 3*time.Second)
+		if err == nil {
+			conn.Close()
+			usedPorts[port] = true
+			continue
+		}
+
+		logger.Infof("✓ Selected SSH port: %d", port)
+		return port, nil
+	}
+
+	return 0, fmt.Errorf("no available ports in range %d-%d", sshPortRangeStart, sshPortRangeEnd)
+}
+
+// inactivityComposeEnv returns the compose environment (the Dokploy env tab) holding the token the
+// workspace uses to stop itself, so the token stays out of the compose file
+func inactivityComposeEnv(opts *options.Options) string {
+	if opts.InactivityTimeout <= 0 {
+		return ""
+	}
+	return "DEVPOD_INACTIVITY_TOKEN=" + opts.InactivityAPIToken
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// testLogger returns a logger that discards its output
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// testOptions returns the options LoadFromEnv yields with only the required variables set
+func testOptions() *options.Options {
+	return &options.Options{
+		DokployServerURL:   "https://dokploy.example.com",
+		DokployAPIToken:    "token",
+		DokployProjectName: "devpod-workspaces",
+		MachineType:        "small",
+		Isolation:          options.IsolationPrivileged,
+		WorkspaceImage:     options.DefaultWorkspaceImage,
+	}
+}
+
+// renderTestCompose renders the compose file of machine "ws" from the given options
+func renderTestCompose(t *testing.T, opts *options.Options) string {
+	t.Helper()
+	params, err := buildComposeParams(opts, testLogger())
+	if err != nil {
+		t.Fatalf("buildComposeParams() error = %v", err)
+	}
+	params.MachineID = "ws"
+	params.SSHPort = 2222
+	params.SSHPublicKey = `ssh-ed25519 AAAAC3Nza "devpod"`
+	params.ComposeID = "compose-1"
+	compose, err := generateDockerCompose(params, testLogger())
+	if err != nil {
+		t.Fatalf("generateDockerCompose() error = %v", err)
+	}
+	return compose
+}
+
+func TestReplaceLinePlaceholder(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		lines   []string
+		want    string
+	}{
+		{
+			name:    "keeps indentation",
+			content: "a:\n    __X__\nb: 1",
+			lines:   []string{"c: 1", "d:", "  - 2"},
+			want:    "a:\n    c: 1\n    d:\n      - 2\nb: 1",
+		},
+		{
+			name:    "drops the line when empty",
+			content: "a:\n  __X__\nb: 1",
+			want:    "a:\nb: 1",
+		},
+		{
+			name:    "replaces every occurrence",
+			content: "  __X__\n__X__",
+			lines:   []string{"y"},
+			want:    "  y\ny",
+		},
+		{
+			name:    "no placeholder",
+			content: "a: 1\n",
+			lines:   []string{"y"},
+			want:    "a: 1\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceLinePlaceholder(tt.content, "__X__", tt.lines); got != tt.want {
+				t.Errorf("replaceLinePlaceholder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateDockerComposeIsValidYAML(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*options.Options)
+	}{
+		{name: "defaults", modify: func(*options.Options) {}},
+		{name: "sysbox", modify: func(o *options.Options) { o.Isolation = options.IsolationSysbox }},
+		{name: "rootless", modify: func(o *options.Options) { o.Isolation = options.IsolationRootless }},
+		{name: "sidecars", modify: func(o *options.Options) {
+			o.Sidecars = "services:\n  postgres:\n    image: postgres:16\n    volumes:\n      - pgdata:/var/lib/postgresql/data\nvolumes:\n  pgdata: {}\n"
+		}},
+		{name: "networks and cache", modify: func(o *options.Options) {
+			o.JoinDokployNetwork = true
+			o.AllowedServices = []string{"postgres:5432"}
+			o.SharedCache = true
+		}},
+		{name: "daemon config and hooks", modify: func(o *options.Options) {
+			o.DockerRegistryMirrors = []string{"https://mirror.example.com"}
+			o.DockerMTU = 1400
+			o.PostSetupScriptValue = "echo hello\necho world"
+			o.DotfilesURL = "https://github.com/example/dotfiles"
+		}},
+		{name: "inactivity timeout", modify: func(o *options.Options) {
+			o.InactivityTimeout = 30 * time.Minute
+			o.InactivityAPIToken = "idle-token"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			tt.modify(opts)
+			compose := renderTestCompose(t, opts)
+
+			if strings.Contains(compose, "PLACEHOLDER") {
+				t.Fatalf("compose file contains an unreplaced placeholder:\n%s", compose)
+			}
+
+			var document struct {
+				Services map[string]map[string]any `yaml:"services"`
+				Volumes  map[string]any            `yaml:"volumes"`
+				Networks map[string]any            `yaml:"networks"`
+			}
+			if err := yaml.Unmarshal([]byte(compose), &document); err != nil {
+				t.Fatalf("compose file is not valid YAML: %v\n%s", err, compose)
+			}
+			workspace, ok := document.Services["devpod-workspace"]
+			if !ok {
+				t.Fatalf("devpod-workspace service missing")
+			}
+			if privileged := workspace["privileged"]; privileged != (opts.Isolation == options.IsolationPrivileged) {
+				t.Errorf("privileged = %v for isolation %s", privileged, opts.Isolation)
+			}
+			if opts.Isolation == options.IsolationSysbox && workspace["runtime"] != "sysbox-runc" {
+				t.Errorf("runtime = %v, want sysbox-runc", workspace["runtime"])
+			}
+			for _, volume := range []string{"docker-data", "workspace-data", "ssh-host-keys"} {
+				if _, ok := document.Volumes[volume]; !ok {
+					t.Errorf("volume %s missing", volume)
+				}
+			}
+
+			marker, err := parseWorkspaceMarker(compose)
+			if err != nil || marker == nil {
+				t.Fatalf("parseWorkspaceMarker() = %v, %v", marker, err)
+			}
+			want := workspaceMarker{
+				ManagedBy:       providerMarker,
+				MachineID:       "ws",
+				SSHPort:         2222,
+				MachineType:     opts.MachineType,
+				Isolation:       opts.Isolation,
+				Image:           opts.WorkspaceImage,
+				TemplateVersion: templateVersion,
+			}
+			recorded := *marker
+			marker.Settings = nil
+			if *marker != want {
+				t.Errorf("marker = %+v, want %+v", *marker, want)
+			}
+
+			// The recorded settings render the same file, whatever the options of the caller
+			if recorded.Settings == nil {
+				t.Fatalf("marker has no settings:\n%s", compose)
+			}
+			recordedOpts, err := markerOptions(testOptions(), &recorded)
+			if err != nil {
+				t.Fatalf("markerOptions() error = %v", err)
+			}
+			recordedOpts.InactivityAPIToken = opts.InactivityAPIToken
+			if got := renderTestCompose(t, recordedOpts); got != compose {
+				t.Errorf("compose file rendered from the recorded settings differs:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestGenerateDockerComposeSSHHost(t *testing.T) {
+	params, err := buildComposeParams(testOptions(), testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	params.MachineID = "ws"
+	params.SSHPort = 2230
+	params.SSHHost = "10.0.0.5"
+	compose, err := generateDockerCompose(params, testLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker, err := parseWorkspaceMarker(compose)
+	if err != nil || marker == nil {
+		t.Fatalf("parseWorkspaceMarker() = %v, %v", marker, err)
+	}
+	if marker.SSHHost != "10.0.0.5" || marker.SSHPort != 2230 {
+		t.Errorf("marker ssh endpoint = %s:%d, want 10.0.0.5:2230", marker.SSHHost, marker.SSHPort)
+	}
+}
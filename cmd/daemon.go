@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+)
+
+// dockerDaemonConfig is the subset of /etc/docker/daemon.json the provider manages
+type dockerDaemonConfig struct {
+	RegistryMirrors     []string            `json:"registry-mirrors,omitempty"`
+	InsecureRegistries  []string            `json:"insecure-registries,omitempty"`
+	DefaultAddressPools []dockerAddressPool `json:"default-address-pools,omitempty"`
+	MTU                 int                 `json:"mtu,omitempty"`
+	LogDriver           string              `json:"log-driver,omitempty"`
+	LogOpts             map[string]string   `json:"log-opts,omitempty"`
+	StorageDriver       string              `json:"storage-driver,omitempty"`
+}
+
+// dockerAddressPool is an entry of default-address-pools
+type dockerAddressPool struct {
+	Base string `json:"base"`
+	Size int    `json:"size"`
+}
+
+// buildDockerDaemonConfig builds the inner dockerd configuration from the provider options.
+// It returns nil when no daemon option is set, leaving the image defaults untouched.
+func buildDockerDaemonConfig(opts *options.Options) (*dockerDaemonConfig, error) {
+	config := &dockerDaemonConfig{
+		RegistryMirrors:    opts.DockerRegistryMirrors,
+		InsecureRegistries: opts.DockerInsecureRegistries,
+		MTU:                opts.DockerMTU,
+		StorageDriver:      opts.DockerStorageDriver,
+	}
+
+	// The shared cache registry goes first so pulls hit it before any other mirror
+	if opts.SharedCache {
+		config.RegistryMirrors = append([]string{cacheMirrorURL}, config.RegistryMirrors...)
+	}
+
+	if opts.DockerAddressPool != "" {
+		pool, err := parseAddressPool(opts.DockerAddressPool)
+		if err != nil {
+			return nil, err
+		}
+		config.DefaultAddressPools = []dockerAddressPool{pool}
+	}
+
+	if opts.DockerMTU < 0 {
+		return nil, fmt.Errorf("DOKPLOY_DOCKER_MTU must be positive, got %d", opts.DockerMTU)
+	}
+
+	if opts.DockerLogMaxSize != "" || opts.DockerLogMaxFile > 0 {
+		config.LogDriver = "json-file"
+		config.LogOpts = map[string]string{}
+		if opts.DockerLogMaxSize != "" {
+			config.LogOpts["max-size"] = opts.DockerLogMaxSize
+		}
+		if opts.DockerLogMaxFile > 0 {
+			config.LogOpts["max-file"] = strconv.Itoa(opts.DockerLogMaxFile)
+		}
+	}
+
+	for _, mirror := range config.RegistryMirrors {
+		if !strings.HasPrefix(mirror, "http://") && !strings.HasPrefix(mirror, "https://") {
+			return nil, fmt.Errorf("registry mirror '%s' must start with http:// or https://", mirror)
+		}
+	}
+
+	if len(config.RegistryMirrors) == 0 && len(config.InsecureRegistries) == 0 && len(config.DefaultAddressPools) == 0 &&
+		config.MTU == 0 && config.LogDriver == "" && config.StorageDriver == "" {
+		return nil, nil
+	}
+
+	return config, nil
+}
+
+// encodeDockerDaemonConfig renders the daemon configuration as base64 encoded JSON for the
+// container environment. An empty string means no configuration.
+func encodeDockerDaemonConfig(config *dockerDaemonConfig) (string, error) {
+	if config == nil {
+		return "", nil
+	}
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to render Docker daemon configuration: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// parseAddressPool parses a default address pool of the form base-cidr:size, e.g. 10.201.0.0/16:24
+func parseAddressPool(value string) (dockerAddressPool, error) {
+	base, sizeValue, found := strings.Cut(value, ":")
+	if !found {
+		return dockerAddressPool{}, fmt.Errorf("DOKPLOY_DOCKER_ADDRESS_POOL must have the form base-cidr:size (e.g. 10.201.0.0/16:24), got '%s'", value)
+	}
+
+	_, network, err := net.ParseCIDR(base)
+	if err != nil {
+		return dockerAddressPool{}, fmt.Errorf("invalid DOKPLOY_DOCKER_ADDRESS_POOL base '%s': %w", base, err)
+	}
+	size, err := strconv.Atoi(sizeValue)
+	if err != nil {
+		return dockerAddressPool{}, fmt.Errorf("invalid DOKPLOY_DOCKER_ADDRESS_POOL size '%s'", sizeValue)
+	}
+	if ones, bits := network.Mask.Size(); size < ones || size > bits {
+		return dockerAddressPool{}, fmt.Errorf("DOKPLOY_DOCKER_ADDRESS_POOL size %d must be between /%d and /%d", size, ones, bits)
+	}
+
+	return dockerAddressPool{Base: network.String(), Size: size}, nil
+}
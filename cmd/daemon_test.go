@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+)
+
+func TestParseAddressPool(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    dockerAddressPool
+		wantErr bool
+	}{
+		{value: "10.201.0.0/16:24", want: dockerAddressPool{Base: "10.201.0.0/16", Size: 24}},
+		{value: "10.201.5.7/16:16", want: dockerAddressPool{Base: "10.201.0.0/16", Size: 16}},
+		{value: "10.201.0.0/16", wantErr: true},
+		{value: "10.201.0.0:24", wantErr: true},
+		{value: "10.201.0.0/16:x", wantErr: true},
+		{value: "10.201.0.0/16:8", wantErr: true},
+		{value: "10.201.0.0/16:33", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAddressPool(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAddressPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseAddressPool() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDockerDaemonConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    options.Options
+		want    *dockerDaemonConfig
+		wantErr bool
+	}{
+		{name: "no options", opts: options.Options{}, want: nil},
+		{
+			name: "all options",
+			opts: options.Options{
+				DockerRegistryMirrors:    []string{"https://mirror.example.com"},
+				DockerInsecureRegistries: []string{"registry.local:5000"},
+				DockerAddressPool:        "10.201.0.0/16:24",
+				DockerMTU:                1400,
+				DockerLogMaxSize:         "10m",
+				DockerLogMaxFile:         3,
+				DockerStorageDriver:      "overlay2",
+			},
+			want: &dockerDaemonConfig{
+				RegistryMirrors:     []string{"https://mirror.example.com"},
+				InsecureRegistries:  []string{"registry.local:5000"},
+				DefaultAddressPools: []dockerAddressPool{{Base: "10.201.0.0/16", Size: 24}},
+				MTU:                 1400,
+				LogDriver:           "json-file",
+				LogOpts:             map[string]string{"max-size": "10m", "max-file": "3"},
+				StorageDriver:       "overlay2",
+			},
+		},
+		{
+			name: "shared cache mirror goes first",
+			opts: options.Options{SharedCache: true, DockerRegistryMirrors: []string{"https://mirror.example.com"}},
+			want: &dockerDaemonConfig{RegistryMirrors: []string{cacheMirrorURL, "https://mirror.example.com"}},
+		},
+		{name: "mirror without scheme", opts: options.Options{DockerRegistryMirrors: []string{"mirror.example.com"}}, wantErr: true},
+		{name: "negative MTU", opts: options.Options{DockerMTU: -1}, wantErr: true},
+		{name: "invalid address pool", opts: options.Options{DockerAddressPool: "10.0.0.0"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildDockerDaemonConfig(&tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildDockerDaemonConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildDockerDaemonConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeDockerDaemonConfig(t *testing.T) {
+	if encoded, err := encodeDockerDaemonConfig(nil); err != nil || encoded != "" {
+		t.Errorf("encodeDockerDaemonConfig(nil) = %q, %v, want empty", encoded, err)
+	}
+
+	encoded, err := encodeDockerDaemonConfig(&dockerDaemonConfig{MTU: 1400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, map[string]interface{}{"mtu": float64(1400)}) {
+		t.Errorf("daemon.json = %s, want only mtu", data)
+	}
+}
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
@@ -9,18 +11,28 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var (
+	deleteForce   bool
+	deleteTimeout time.Duration
+)
+
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
 	Use:   "delete",
 	Short: "Delete a Dokploy workspace",
 	Long: `Delete an existing development workspace from Dokploy.
-This will remove the Docker Compose service and all associated resources.`,
+This will remove the Docker Compose service and all associated resources.
+
+Only compose services in the configured project that were created by this provider are deleted.
+Deleting a workspace that no longer exists succeeds.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runDelete()
 	},
 }
 
 func init() {
+	deleteCmd.Flags().BoolVar(&deleteForce, "force", false, "delete the compose service even if it was not created by the DevPod provider")
+	deleteCmd.Flags().DurationVar(&deleteTimeout, "timeout", 2*time.Minute, "how long to wait for the deletion to finish")
 	rootCmd.AddCommand(deleteCmd)
 }
 
@@ -47,12 +59,108 @@ func runDelete() error {
 	// Create Dokploy client
 	client := dokploy.NewClient(opts, logger)
 
-	// Delete the Docker Compose service
-	err = client.DeleteComposeByName(machineID)
+	// Only look inside the configured project
+	projectID, err := findProjectID(client, opts.DokployProjectName)
+	if err != nil {
+		return err
+	}
+	if projectID == "" {
+		logger.Infof("✓ Project '%s' does not exist, workspace %s is already deleted", opts.DokployProjectName, machineID)
+		return nil
+	}
+
+	matches, err := client.FindComposesInProject(projectID, machineID)
 	if err != nil {
-		return fmt.Errorf("failed to delete Docker Compose service: %w", err)
+		return fmt.Errorf("failed to look up Docker Compose service: %w", err)
+	}
+	if len(matches) == 0 {
+		logger.Infof("✓ Workspace %s is already deleted", machineID)
+		return nil
+	}
+
+	// Refuse to delete services this provider did not create, unless forced
+	var targets []*dokploy.Compose
+	for _, match := range matches {
+		compose, err := client.GetCompose(match.ComposeID)
+		if err != nil {
+			return fmt.Errorf("failed to get compose service %s: %w", match.ComposeID, err)
+		}
+		if !isProviderOwned(compose, machineID) {
+			if !deleteForce {
+				return fmt.Errorf("compose service '%s' (%s) in project '%s' was not created by the DevPod provider; "+
+					"refusing to delete it (use --force to delete it anyway)", machineID, compose.ComposeID, opts.DokployProjectName)
+			}
+			logger.Warnf("Deleting compose service %s although it was not created by the DevPod provider (--force)", compose.ComposeID)
+		}
+		targets = append(targets, compose)
+	}
+
+	// Delete the Docker Compose service, keeping named volumes if requested
+	if opts.RetainVolumesOnDelete {
+		logger.Info("Retaining workspace volumes (DOKPLOY_RETAIN_VOLUMES_ON_DELETE=true)")
+	}
+	for _, compose := range targets {
+		logger.Infof("Deleting compose service %s...", compose.ComposeID)
+		if err := client.DeleteCompose(compose.ComposeID, !opts.RetainVolumesOnDelete); err != nil {
+			// A concurrent delete may have removed it already; the wait below confirms it
+			if !errors.Is(err, dokploy.ErrNotFound) {
+				return fmt.Errorf("failed to delete compose service %s: %w", compose.ComposeID, err)
+			}
+			logger.Infof("Compose service %s is already gone", compose.ComposeID)
+		}
+	}
+
+	if err := waitForComposesDeleted(client, projectID, machineID, targets, deleteTimeout, logger); err != nil {
+		return err
 	}
 
 	logger.Info("✓ Dokploy workspace deleted (Docker Compose service removed)")
+	if opts.RetainVolumesOnDelete {
+		logger.Infof("✓ Volumes %s and %s kept; creating '%s' again will reattach them",
+			dockerVolumeName(machineID), workspaceVolumeName(machineID), machineID)
+	}
 	return nil
-} 
\ No newline at end of file
+}
+
+// waitForComposesDeleted waits until none of the given compose services is listed in the project anymore
+func waitForComposesDeleted(client *dokploy.Client, projectID, name string, composes []*dokploy.Compose, timeout time.Duration, logger *logrus.Logger) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining, err := client.FindComposesInProject(projectID, name)
+		if err != nil {
+			logger.Debugf("Failed to list compose services: %v", err)
+		} else {
+			pending := 0
+			for _, compose := range composes {
+				for _, current := range remaining {
+					if current.ComposeID == compose.ComposeID {
+						pending++
+					}
+				}
+			}
+			if pending == 0 {
+				return nil
+			}
+			logger.Infof("   Waiting for %d compose service(s) to be removed...", pending)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%w to be deleted after %v; check the compose service in the Dokploy dashboard", errWaitTimeout, timeout)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// findProjectID returns the ID of the project with the given name, or an empty string when it does not exist
+func findProjectID(client *dokploy.Client, projectName string) (string, error) {
+	projects, err := client.GetAllProjects()
+	if err != nil {
+		return "", fmt.Errorf("failed to get projects: %w", err)
+	}
+	for _, project := range projects {
+		if project.Name == projectName {
+			return project.ProjectID, nil
+		}
+	}
+	return "", nil
+}
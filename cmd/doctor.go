@@ -0,0 +1,391 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Results of a doctor check
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+const (
+	// sshPortRangeStart and sshPortRangeEnd bound the host ports published for workspace SSH
+	sshPortRangeStart = 2222
+	sshPortRangeEnd   = 2250
+
+	// maxClockSkew is the clock difference to the Dokploy server tolerated without a warning
+	maxClockSkew = 30 * time.Second
+)
+
+var doctorJSON bool
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the Dokploy provider environment",
+	Long: `Run end-to-end checks of the provider configuration, the Dokploy API token, the SSH
+port range, dokploy-network, the isolation mode and the clocks. Each check reports pass,
+warn or fail with a hint on how to fix it. The command fails when a check fails.
+
+Some checks create and delete a temporary compose service in the configured project.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDoctor()
+	},
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "print the results as JSON")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// checkResult is the outcome of one doctor check
+type checkResult struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// doctorEnv is the state shared between doctor checks
+type doctorEnv struct {
+	opts      *options.Options
+	client    *dokploy.Client
+	logger    *logrus.Logger
+	apiOK     bool
+	projectID string
+}
+
+// doctorCheck is a single diagnostic. Quick checks are also run by init.
+type doctorCheck struct {
+	name  string
+	quick bool
+	run   func(env *doctorEnv) checkResult
+}
+
+// doctorChecks lists the checks in the order they run; later checks rely on the state earlier ones record
+var doctorChecks = []doctorCheck{
+	{name: "configuration", quick: true, run: checkConfiguration},
+	{name: "api", quick: true, run: checkAPI},
+	{name: "project", quick: true, run: checkProject},
+	{name: "ssh-host", quick: true, run: checkSSHHost},
+	{name: "clock", quick: true, run: checkClock},
+	{name: "compose-permissions", run: checkComposePermissions},
+	{name: "ssh-ports", run: checkSSHPorts},
+	{name: "dokploy-network", run: checkDokployNetwork},
+	{name: "isolation", run: checkIsolation},
+}
+
+func runDoctor() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	} else {
+		// Checks report through their results; keep helper output out of the report
+		logger.SetLevel(logrus.WarnLevel)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	results := runDoctorChecks(opts, logger, false)
+
+	failed := 0
+	for _, result := range results {
+		if result.Status == checkFail {
+			failed++
+		}
+	}
+
+	if doctorJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			OK     bool          `json:"ok"`
+			Checks []checkResult `json:"checks"`
+		}{OK: failed == 0, Checks: results}); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			fmt.Printf("%s %-20s %s\n", checkSymbol(result.Status), result.Name, result.Message)
+			if result.Hint != "" {
+				fmt.Printf("  %-20s → %s\n", "", result.Hint)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed", failed)
+	}
+	return nil
+}
+
+// runDoctorChecks runs all checks, or only the quick ones
+func runDoctorChecks(opts *options.Options, logger *logrus.Logger, quickOnly bool) []checkResult {
+	env := &doctorEnv{opts: opts, client: dokploy.NewClient(opts, logger), logger: logger}
+
+	var results []checkResult
+	for _, check := range doctorChecks {
+		if quickOnly && !check.quick {
+			continue
+		}
+		result := check.run(env)
+		result.Name = check.name
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkSymbol returns the marker printed in front of a check result
+func checkSymbol(status string) string {
+	switch status {
+	case checkPass:
+		return "✓"
+	case checkWarn:
+		return "⚠️ "
+	case checkSkip:
+		return "-"
+	default:
+		return "✗"
+	}
+}
+
+func checkConfiguration(env *doctorEnv) checkResult {
+	if _, err := env.opts.ResolveMachineType(); err != nil {
+		return checkResult{Status: checkFail, Message: err.Error(),
+			Hint: "set MACHINE_TYPE to a built-in type or one defined in DOKPLOY_MACHINE_TYPES(_FILE)"}
+	}
+	if _, err := buildDockerDaemonConfig(env.opts); err != nil {
+		return checkResult{Status: checkFail, Message: fmt.Sprintf("invalid inner Docker daemon options: %v", err),
+			Hint: "fix the DOKPLOY_DOCKER_* options"}
+	}
+	if env.opts.InactivityTimeout > 0 && env.opts.InactivityAPIToken == "" {
+		return checkResult{Status: checkFail, Message: "DOKPLOY_INACTIVITY_TIMEOUT is set without DOKPLOY_INACTIVITY_API_TOKEN",
+			Hint: "create a Dokploy user restricted to the workspace project and set its API token"}
+	}
+	return checkResult{Status: checkPass, Message: fmt.Sprintf("machine type '%s', %s isolation", env.opts.MachineType, env.opts.Isolation)}
+}
+
+func checkAPI(env *doctorEnv) checkResult {
+	if err := env.client.HealthCheck(); err != nil {
+		return checkResult{Status: checkFail, Message: err.Error(),
+			Hint: "check DOKPLOY_SERVER_URL, that the server is reachable from here and that DOKPLOY_API_TOKEN is valid"}
+	}
+	env.apiOK = true
+	return checkResult{Status: checkPass, Message: fmt.Sprintf("%s is reachable", env.opts.DokployServerURL)}
+}
+
+func checkProject(env *doctorEnv) checkResult {
+	if !env.apiOK {
+		return skipped("the Dokploy API is not reachable")
+	}
+	projectID, err := findProjectID(env.client, env.opts.DokployProjectName)
+	if err != nil {
+		return checkResult{Status: checkFail, Message: err.Error(), Hint: "give the API token's user access to projects"}
+	}
+	if projectID == "" {
+		return checkResult{Status: checkWarn, Message: fmt.Sprintf("project '%s' does not exist yet", env.opts.DokployProjectName),
+			Hint: "it is created by the first workspace; create it in Dokploy to run all checks now"}
+	}
+	env.projectID = projectID
+	return checkResult{Status: checkPass, Message: fmt.Sprintf("project '%s' exists", env.opts.DokployProjectName)}
+}
+
+func checkSSHHost(env *doctorEnv) checkResult {
+	host, err := sshHostFromURL(env.opts.DokployServerURL)
+	if err != nil {
+		return checkResult{Status: checkFail, Message: err.Error(), Hint: "set DOKPLOY_SERVER_URL to a URL such as https://dokploy.example.com"}
+	}
+	if net.ParseIP(host) != nil {
+		return checkResult{Status: checkPass, Message: fmt.Sprintf("SSH host is the IP address %s", host)}
+	}
+	addresses, err := net.LookupHost(host)
+	if err != nil {
+		return checkResult{Status: checkFail, Message: fmt.Sprintf("SSH host %s does not resolve: %v", host, err),
+			Hint: "workspaces are reached on the host of DOKPLOY_SERVER_URL; check its DNS record"}
+	}
+	return checkResult{Status: checkPass, Message: fmt.Sprintf("SSH host %s resolves to %s", host, strings.Join(addresses, ", "))}
+}
+
+func checkClock(env *doctorEnv) checkResult {
+	if !env.apiOK {
+		return skipped("the Dokploy API is not reachable")
+	}
+	serverTime, err := env.client.ServerTime()
+	if err != nil {
+		return checkResult{Status: checkWarn, Message: err.Error(), Hint: "a proxy in front of Dokploy may strip the Date header"}
+	}
+	skew := time.Since(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+	// The Date header has a one second resolution
+	if skew > maxClockSkew+time.Second {
+		return checkResult{Status: checkWarn, Message: fmt.Sprintf("local clock differs from the Dokploy server by %v", skew.Round(time.Second)),
+			Hint: "sync both clocks with NTP; gc ages and timeouts are computed from Dokploy timestamps"}
+	}
+	return checkResult{Status: checkPass, Message: fmt.Sprintf("clocks agree (skew %v)", skew.Round(time.Second))}
+}
+
+func checkComposePermissions(env *doctorEnv) checkResult {
+	if env.projectID == "" {
+		return skipped("the project does not exist or the API is not reachable")
+	}
+	probe, err := env.client.CreateCompose(dokploy.CreateComposeRequest{
+		Name:        fmt.Sprintf("devpod-doctor-%d", time.Now().Unix()),
+		Description: "Temporary permission check created by the DevPod provider doctor",
+		ProjectID:   env.projectID,
+		ComposeType: "docker-compose",
+	})
+	if err != nil {
+		return checkResult{Status: checkFail, Message: fmt.Sprintf("cannot create compose services: %v", err),
+			Hint: "use an API token of a user allowed to create services in the project"}
+	}
+	if err := env.client.DeleteCompose(probe.ComposeID, true); err != nil {
+		return checkResult{Status: checkFail, Message: fmt.Sprintf("cannot delete compose services: %v", err),
+			Hint: fmt.Sprintf("use an API token of a user allowed to delete services; remove %s by hand", probe.ComposeID)}
+	}
+	return checkResult{Status: checkPass, Message: "the API token can create and delete compose services"}
+}
+
+func checkSSHPorts(env *doctorEnv) checkResult {
+	host, err := sshHostFromURL(env.opts.DokployServerURL)
+	if err != nil {
+		return skipped("the SSH host is unknown")
+	}
+
+	// A refused connection proves the port is reachable and free; a timeout means it is filtered
+	var mutex sync.Mutex
+	var wait sync.WaitGroup
+	var free, used, filtered int
+	for port := sshPortRangeStart; port <= sshPortRangeEnd; port++ {
+		wait.Add(1)
+		go func(port int) {
+			defer wait.Done()
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), 3*time.Second)
+			mutex.Lock()
+			defer mutex.Unlock()
+			var netErr net.Error
+			switch {
+			case err == nil:
+				conn.Close()
+				used++
+			case errors.As(err, &netErr) && netErr.Timeout():
+				filtered++
+			default:
+				free++
+			}
+		}(port)
+	}
+	wait.Wait()
+
+	message := fmt.Sprintf("ports %d-%d: %d free, %d in use, %d filtered", sshPortRangeStart, sshPortRangeEnd, free, used, filtered)
+	switch {
+	case free == 0 && used == 0:
+		return checkResult{Status: checkFail, Message: message,
+			Hint: fmt.Sprintf("allow TCP %d-%d to the Dokploy host in its firewall", sshPortRangeStart, sshPortRangeEnd)}
+	case free == 0:
+		return checkResult{Status: checkFail, Message: message,
+			Hint: "no port is left for new workspaces; remove unused ones with `dokploy-provider gc`"}
+	case filtered > 0:
+		return checkResult{Status: checkWarn, Message: message,
+			Hint: fmt.Sprintf("some ports do not answer; allow TCP %d-%d in the firewall", sshPortRangeStart, sshPortRangeEnd)}
+	}
+	return checkResult{Status: checkPass, Message: message}
+}
+
+func checkDokployNetwork(env *doctorEnv) checkResult {
+	if !env.apiOK {
+		return skipped("the Dokploy API is not reachable")
+	}
+
+	// Only some configurations need the network; a problem is then a failure, otherwise a warning
+	severity := checkWarn
+	if env.opts.JoinDokployNetwork || len(env.opts.AllowedServices) > 0 || env.opts.SharedCache {
+		severity = checkFail
+	}
+
+	// Traefik always runs on dokploy-network, so its container shows whether the network exists
+	containers, err := env.client.GetContainers("")
+	if err != nil {
+		return checkResult{Status: severity, Message: fmt.Sprintf("cannot list containers: %v", err),
+			Hint: "use an API token of a user allowed to read Docker containers"}
+	}
+	for _, container := range containers {
+		if !strings.Contains(container.Name, "dokploy-traefik") {
+			continue
+		}
+		inspect, err := env.client.InspectContainer(container.ContainerID, "")
+		if err != nil {
+			return checkResult{Status: severity, Message: fmt.Sprintf("cannot inspect %s: %v", container.Name, err)}
+		}
+		if _, ok := inspect.NetworkSettings.Networks["dokploy-network"]; ok {
+			return checkResult{Status: checkPass, Message: "dokploy-network exists"}
+		}
+		break
+	}
+	return checkResult{Status: severity, Message: "dokploy-network was not found",
+		Hint: "it is created by the Dokploy installer; DOKPLOY_JOIN_DOKPLOY_NETWORK, DOKPLOY_ALLOWED_SERVICES and DOKPLOY_SHARED_CACHE need it"}
+}
+
+func checkIsolation(env *doctorEnv) checkResult {
+	switch env.opts.Isolation {
+	case options.IsolationSysbox:
+		if env.projectID == "" {
+			return skipped("the project does not exist or the API is not reachable")
+		}
+		if err := verifyIsolationSupport(env.client, env.projectID, env.opts, env.logger); err != nil {
+			return checkResult{Status: checkFail, Message: err.Error(),
+				Hint: "install sysbox on the Dokploy node or set DOKPLOY_ISOLATION=privileged"}
+		}
+		return checkResult{Status: checkPass, Message: "the sysbox-runc runtime is available"}
+	case options.IsolationRootless:
+		if env.opts.WorkspaceImage == options.DefaultWorkspaceImage {
+			return checkResult{Status: checkFail, Message: "the default workspace image has no rootless Docker daemon",
+				Hint: "build an image with `dokploy-provider build-image` and set DOKPLOY_WORKSPACE_IMAGE"}
+		}
+		return checkResult{Status: checkWarn, Message: "user namespace support can only be verified when a workspace starts",
+			Hint: "if setup fails, enable unprivileged user namespaces on the node (kernel.unprivileged_userns_clone=1)"}
+	default:
+		return checkResult{Status: checkPass, Message: "privileged workspaces need no extra runtime"}
+	}
+}
+
+// skipped returns the result of a check that could not run
+func skipped(reason string) checkResult {
+	return checkResult{Status: checkSkip, Message: "skipped: " + reason}
+}
+
+// sshHostFromURL returns the host workspaces are reached on over SSH
+func sshHostFromURL(serverURL string) (string, error) {
+	parsedURL, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse server URL: %w", err)
+	}
+	if parsedURL.Hostname() == "" {
+		return "", fmt.Errorf("server URL '%s' has no host", serverURL)
+	}
+	return parsedURL.Hostname(), nil
+}
@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	devpodconfig "github.com/loft-sh/devpod/pkg/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcTTL      time.Duration
+	gcIdle     time.Duration
+	gcErrored  bool
+	gcOrphans  bool
+	gcDryRun   bool
+	gcYes      bool
+	gcProjects []string
+)
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete failed, expired and orphaned workspaces",
+	Long: `Find workspaces created by this provider that are no longer needed and delete them:
+
+  - workspaces whose compose service is in error state (--errored, on by default)
+  - workspaces whose machine no longer exists in the local DevPod directory (--orphans, off by default)
+  - workspaces created longer ago than --ttl
+  - stopped workspaces not deployed for longer than --idle
+
+The plan is printed first. Use --dry-run to stop there, or --yes to delete without asking.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runGC()
+	},
+}
+
+func init() {
+	gcCmd.Flags().DurationVar(&gcTTL, "ttl", 0, "delete workspaces created longer ago than this (0 disables)")
+	gcCmd.Flags().DurationVar(&gcIdle, "idle", 0, "delete stopped workspaces whose last deployment is older than this (0 disables)")
+	gcCmd.Flags().BoolVar(&gcErrored, "errored", true, "delete workspaces whose compose service is in error state")
+	gcCmd.Flags().BoolVar(&gcOrphans, "orphans", false, "delete workspaces whose machine no longer exists in the local DevPod directory (only safe when nobody else creates workspaces in the project)")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "only print the plan")
+	gcCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "delete without asking for confirmation")
+	gcCmd.Flags().StringSliceVar(&gcProjects, "project", nil, "project(s) to clean up (default: DOKPLOY_PROJECT_NAME)")
+	rootCmd.AddCommand(gcCmd)
+}
+
+// gcCandidate is a workspace selected for deletion, with the reasons it was selected
+type gcCandidate struct {
+	workspace workspaceInfo
+	reasons   []string
+}
+
+func runGC() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	dokployClient := dokploy.NewClient(opts, logger)
+
+	projectNames := gcProjects
+	if len(projectNames) == 0 {
+		projectNames = []string{opts.DokployProjectName}
+	}
+
+	workspaces, err := listWorkspaces(dokployClient, opts, projectNames, logger)
+	if err != nil {
+		return err
+	}
+
+	var localMachines map[string]bool
+	if gcOrphans {
+		localMachines, err = findLocalMachines()
+		if err != nil {
+			return err
+		}
+		if localMachines == nil {
+			logger.Warn("No local DevPod directory found, skipping the orphan check")
+		}
+	}
+
+	candidates := selectGCCandidates(workspaces, localMachines, time.Now(), logger)
+	if len(candidates) == 0 {
+		logger.Info("✓ Nothing to clean up")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "MACHINE\tCOMPOSE ID\tPROJECT\tSTATUS\tREASON")
+	for _, candidate := range candidates {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n",
+			candidate.workspace.MachineID, candidate.workspace.ComposeID, candidate.workspace.Project,
+			candidate.workspace.DokployStatus, strings.Join(candidate.reasons, ", "))
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	if gcDryRun {
+		logger.Infof("Dry run: %d workspace(s) would be deleted", len(candidates))
+		return nil
+	}
+
+	if !gcYes {
+		fmt.Fprintf(os.Stderr, "Delete %d workspace(s)? [y/N] ", len(candidates))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			logger.Info("Aborted, nothing deleted")
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, candidate := range candidates {
+		logger.Infof("Deleting workspace %s (%s)...", candidate.workspace.MachineID, candidate.workspace.ComposeID)
+		if err := dokployClient.DeleteCompose(candidate.workspace.ComposeID, !opts.RetainVolumesOnDelete); err != nil {
+			if errors.Is(err, dokploy.ErrNotFound) {
+				logger.Infof("Workspace %s is already deleted", candidate.workspace.MachineID)
+				continue
+			}
+			logger.Errorf("Failed to delete workspace %s: %v", candidate.workspace.MachineID, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to delete %d of %d workspace(s)", failed, len(candidates))
+	}
+	logger.Infof("✓ Deleted %d workspace(s)", len(candidates))
+	return nil
+}
+
+// selectGCCandidates applies the gc criteria to the workspaces. localMachines is nil when the
+// orphan check is disabled or no DevPod directory exists.
+func selectGCCandidates(workspaces []workspaceInfo, localMachines map[string]bool, now time.Time, logger *logrus.Logger) []gcCandidate {
+	var candidates []gcCandidate
+	for _, workspace := range workspaces {
+		var reasons []string
+
+		if gcErrored && workspace.DokployStatus == "error" {
+			reasons = append(reasons, "compose service in error state")
+		}
+
+		if localMachines != nil && !localMachines[workspace.MachineID] {
+			reasons = append(reasons, "machine not found in DevPod")
+		}
+
+		createdAt, err := parseDokployTime(workspace.CreatedAt)
+		if err != nil {
+			logger.Debugf("Workspace %s has no usable creation time: %v", workspace.MachineID, err)
+		}
+		if gcTTL > 0 && err == nil && now.Sub(createdAt) > gcTTL {
+			reasons = append(reasons, fmt.Sprintf("created %v ago", now.Sub(createdAt).Round(time.Minute)))
+		}
+
+		if gcIdle > 0 && workspace.Status == client.StatusStopped {
+			// Workspaces that were never deployed count from their creation
+			lastActive, err := parseDokployTime(workspace.LastDeploy)
+			if err != nil {
+				lastActive = createdAt
+			}
+			if !lastActive.IsZero() && now.Sub(lastActive) > gcIdle {
+				reasons = append(reasons, fmt.Sprintf("stopped, last deployed %v ago", now.Sub(lastActive).Round(time.Minute)))
+			}
+		}
+
+		if len(reasons) > 0 {
+			candidates = append(candidates, gcCandidate{workspace: workspace, reasons: reasons})
+		}
+	}
+	return candidates
+}
+
+// findLocalMachines returns the IDs of the machines in all DevPod contexts, or nil when there
+// is no local DevPod directory
+func findLocalMachines() (map[string]bool, error) {
+	configDir, err := devpodconfig.GetConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the DevPod directory: %w", err)
+	}
+
+	contextsDir := filepath.Join(configDir, "contexts")
+	if _, err := os.Stat(contextsDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	machineDirs, err := filepath.Glob(filepath.Join(contextsDir, "*", "machines", "*"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list DevPod machines: %w", err)
+	}
+
+	machines := map[string]bool{}
+	for _, machineDir := range machineDirs {
+		machines[filepath.Base(machineDir)] = true
+	}
+	return machines, nil
+}
+
+// parseDokployTime parses the ISO 8601 timestamps returned by the Dokploy API
+func parseDokployTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+)
+
+func TestSelectGCCandidates(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) string {
+		return now.Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
+	}
+
+	tests := []struct {
+		name          string
+		errored       bool
+		ttl           time.Duration
+		idle          time.Duration
+		localMachines map[string]bool
+		workspace     workspaceInfo
+		want          []string
+	}{
+		{
+			name:      "errored",
+			errored:   true,
+			workspace: workspaceInfo{MachineID: "ws", DokployStatus: "error", CreatedAt: daysAgo(1)},
+			want:      []string{"compose service in error state"},
+		},
+		{
+			name:      "errored check disabled",
+			workspace: workspaceInfo{MachineID: "ws", DokployStatus: "error", CreatedAt: daysAgo(1)},
+		},
+		{
+			name:      "orphan check disabled",
+			workspace: workspaceInfo{MachineID: "ws", DokployStatus: "done", CreatedAt: daysAgo(1)},
+		},
+		{
+			name:          "orphan",
+			localMachines: map[string]bool{"other": true},
+			workspace:     workspaceInfo{MachineID: "ws", DokployStatus: "done", CreatedAt: daysAgo(1)},
+			want:          []string{"machine not found in DevPod"},
+		},
+		{
+			name:          "local machine",
+			localMachines: map[string]bool{"ws": true},
+			workspace:     workspaceInfo{MachineID: "ws", DokployStatus: "done", CreatedAt: daysAgo(1)},
+		},
+		{
+			name:      "expired",
+			ttl:       7 * 24 * time.Hour,
+			workspace: workspaceInfo{MachineID: "ws", DokployStatus: "done", CreatedAt: daysAgo(10)},
+			want:      []string{"created 240h0m0s ago"},
+		},
+		{
+			name:      "not expired",
+			ttl:       7 * 24 * time.Hour,
+			workspace: workspaceInfo{MachineID: "ws", DokployStatus: "done", CreatedAt: daysAgo(3)},
+		},
+		{
+			name:      "no creation time",
+			ttl:       time.Hour,
+			workspace: workspaceInfo{MachineID: "ws", DokployStatus: "done"},
+		},
+		{
+			name:      "idle",
+			idle:      48 * time.Hour,
+			workspace: workspaceInfo{MachineID: "ws", Status: client.StatusStopped, CreatedAt: daysAgo(10), LastDeploy: daysAgo(3)},
+			want:      []string{"stopped, last deployed 72h0m0s ago"},
+		},
+		{
+			name:      "idle since creation",
+			idle:      48 * time.Hour,
+			workspace: workspaceInfo{MachineID: "ws", Status: client.StatusStopped, CreatedAt: daysAgo(3)},
+			want:      []string{"stopped, last deployed 72h0m0s ago"},
+		},
+		{
+			name:      "running is never idle",
+			idle:      48 * time.Hour,
+			workspace: workspaceInfo{MachineID: "ws", Status: client.StatusRunning, CreatedAt: daysAgo(10), LastDeploy: daysAgo(10)},
+		},
+		{
+			name:          "several reasons",
+			errored:       true,
+			ttl:           24 * time.Hour,
+			localMachines: map[string]bool{},
+			workspace:     workspaceInfo{MachineID: "ws", DokployStatus: "error", CreatedAt: daysAgo(2)},
+			want:          []string{"compose service in error state", "machine not found in DevPod", "created 48h0m0s ago"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gcErrored, gcTTL, gcIdle = tt.errored, tt.ttl, tt.idle
+			t.Cleanup(func() { gcErrored, gcTTL, gcIdle = true, 0, 0 })
+
+			candidates := selectGCCandidates([]workspaceInfo{tt.workspace}, tt.localMachines, now, testLogger())
+			if tt.want == nil {
+				if len(candidates) != 0 {
+					t.Errorf("selectGCCandidates() = %+v, want none", candidates)
+				}
+				return
+			}
+			if len(candidates) != 1 || !reflect.DeepEqual(candidates[0].reasons, tt.want) {
+				t.Errorf("selectGCCandidates() = %+v, want reasons %q", candidates, tt.want)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 
-	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -14,7 +13,7 @@ var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize and validate the Dokploy provider",
 	Long: `Initialize the Dokploy provider by validating configuration options
-and testing connectivity to the Dokploy server.`,
+and testing connectivity to the Dokploy server. This runs the quick checks of doctor.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runInit()
 	},
@@ -41,43 +40,25 @@ func runInit() error {
 
 	logger.Debug("Configuration loaded successfully")
 
-	// Create Dokploy client
-	client := dokploy.NewClient(opts, logger)
-
-	// Test connection to Dokploy server
-	logger.Info("Testing Dokploy server connection...")
-	if err := client.HealthCheck(); err != nil {
-		return fmt.Errorf("Dokploy server connection failed: %w", err)
-	}
-
-	logger.Info("✓ Dokploy server connection successful")
-
-	// Test SSH connection if we have a machine ID (for existing workspaces)
-	if opts.MachineID != "" {
-		logger.Infof("Testing SSH connection to existing workspace: %s", opts.MachineID)
-		
-		// Get application details to test SSH connectivity
-		app, err := client.GetApplication(opts.MachineID)
-		if err != nil {
-			logger.Warnf("Could not retrieve application details: %v", err)
-		} else {
-			// Find SSH port mapping
-			var sshPort int
-			for _, port := range app.Ports {
-				if port.TargetPort == 22 {
-					sshPort = port.PublishedPort
-					break
-				}
-			}
-
-			if sshPort > 0 {
-				logger.Infof("Found SSH port mapping: %d -> 22", sshPort)
-				logger.Info("✓ SSH port mapping configured")
-			} else {
-				logger.Warn("⚠️  No SSH port mapping found")
-			}
+	// Run the quick doctor checks; `dokploy-provider doctor` runs the full set
+	failed := 0
+	for _, result := range runDoctorChecks(opts, logger, true) {
+		switch result.Status {
+		case checkPass:
+			logger.Infof("✓ %s: %s", result.Name, result.Message)
+		case checkFail:
+			failed++
+			logger.Errorf("✗ %s: %s", result.Name, result.Message)
+		default:
+			logger.Warnf("⚠️  %s: %s", result.Name, result.Message)
+		}
+		if result.Hint != "" && result.Status != checkPass {
+			logger.Infof("   → %s", result.Hint)
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d check(s) failed; run `dokploy-provider doctor` for a full diagnosis", failed)
+	}
 
 	logger.Info("Dokploy provider initialized successfully")
 	return nil
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// sysboxProbeCompose is a throwaway compose file used to check that sysbox-runc is installed on the node
+const sysboxProbeCompose = `services:
+  runtime-check:
+    image: busybox:latest
+    runtime: sysbox-runc
+    command: ["true"]
+    restart: "no"
+`
+
+// isolationComposeLines returns the extra service keys for an isolation mode
+func isolationComposeLines(isolation string) []string {
+	switch isolation {
+	case options.IsolationSysbox:
+		return []string{"runtime: sysbox-runc"}
+	case options.IsolationRootless:
+		// Rootless dockerd needs to create user namespaces and mount /proc inside them
+		return []string{
+			"security_opt:",
+			"  - seccomp=unconfined",
+			"  - apparmor=unconfined",
+			"  - systempaths=unconfined",
+			"devices:",
+			"  - /dev/fuse",
+			"  - /dev/net/tun",
+		}
+	default:
+		return nil
+	}
+}
+
+// needsIsolationCheck reports whether create has to verify the isolation mode. A workspace that
+// already runs with the same isolation proves the node supports it.
+func needsIsolationCheck(opts *options.Options, existingMarker *workspaceMarker) bool {
+	return existingMarker == nil || existingMarker.Isolation != opts.Isolation
+}
+
+// isolationDescription returns a human readable summary of an isolation mode
+func isolationDescription(isolation string) string {
+	switch isolation {
+	case options.IsolationSysbox:
+		return "sysbox (unprivileged, sysbox-runc runtime)"
+	case options.IsolationRootless:
+		return "rootless (unprivileged, rootless inner dockerd)"
+	default:
+		return "privileged (full Docker-in-Docker support)"
+	}
+}
+
+// verifyIsolationSupport checks that the Dokploy node can run workspaces with the given isolation.
+// For sysbox a short-lived probe service is deployed with the sysbox-runc runtime; rootless mode
+// needs an image with the rootless dockerd and verifies user namespace support at setup time.
+func verifyIsolationSupport(client *dokploy.Client, projectID string, opts *options.Options, logger *logrus.Logger) error {
+	switch opts.Isolation {
+	case options.IsolationSysbox:
+		logger.Info("Checking that the Dokploy node provides the sysbox-runc runtime...")
+	case options.IsolationRootless:
+		if opts.WorkspaceImage == options.DefaultWorkspaceImage {
+			return fmt.Errorf("DOKPLOY_ISOLATION=rootless needs a workspace image with the rootless Docker daemon; " +
+				"build one with `dokploy-provider build-image` and set DOKPLOY_WORKSPACE_IMAGE")
+		}
+		logger.Info("Rootless isolation selected: user namespace support is verified when the workspace starts")
+		return nil
+	default:
+		return nil
+	}
+
+	probe, err := client.CreateCompose(dokploy.CreateComposeRequest{
+		Name:        fmt.Sprintf("devpod-runtime-check-%d", time.Now().Unix()),
+		Description: "Temporary sysbox runtime check created by the DevPod provider",
+		ProjectID:   projectID,
+		ComposeType: "docker-compose",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create sysbox runtime check service: %w", err)
+	}
+	defer func() {
+		if err := client.DeleteCompose(probe.ComposeID, true); err != nil {
+			logger.Warnf("Failed to delete sysbox runtime check service %s: %v", probe.ComposeID, err)
+		}
+	}()
+
+	if err := client.SaveComposeFile(dokploy.SaveComposeFileRequest{
+		ComposeID:     probe.ComposeID,
+		DockerCompose: sysboxProbeCompose,
+	}); err != nil {
+		return fmt.Errorf("failed to save sysbox runtime check: %w", err)
+	}
+
+	if err := client.DeployCompose(dokploy.DeployComposeRequest{ComposeID: probe.ComposeID}); err != nil {
+		return fmt.Errorf("failed to deploy sysbox runtime check: %w", err)
+	}
+
+	for i := 1; i <= 24; i++ {
+		time.Sleep(5 * time.Second)
+
+		current, err := client.GetCompose(probe.ComposeID)
+		if err != nil {
+			logger.Debugf("Failed to get sysbox runtime check status: %v", err)
+			continue
+		}
+
+		switch current.Status {
+		case "done":
+			logger.Info("✓ sysbox-runc runtime is available")
+			return nil
+		case "error":
+			return fmt.Errorf("the sysbox-runc runtime is not available on the Dokploy node; " +
+				"install sysbox (https://github.com/nestybox/sysbox) on the node or set DOKPLOY_ISOLATION=privileged")
+		}
+		logger.Debugf("Sysbox runtime check status: %s (attempt %d/24)", current.Status, i)
+	}
+
+	return fmt.Errorf("timed out waiting for the sysbox runtime check to deploy")
+}
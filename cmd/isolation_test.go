@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+)
+
+func TestNeedsIsolationCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		isolation string
+		marker    *workspaceMarker
+		want      bool
+	}{
+		{name: "new workspace", isolation: options.IsolationSysbox, want: true},
+		{name: "reconcile with same isolation", isolation: options.IsolationSysbox, marker: &workspaceMarker{Isolation: options.IsolationSysbox}, want: false},
+		{name: "isolation changed", isolation: options.IsolationSysbox, marker: &workspaceMarker{Isolation: options.IsolationPrivileged}, want: true},
+		{name: "marker without isolation", isolation: options.IsolationSysbox, marker: &workspaceMarker{}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Isolation = tt.isolation
+			if got := needsIsolationCheck(opts, tt.marker); got != tt.want {
+				t.Errorf("needsIsolationCheck() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyIsolationSupportRejectsDefaultImageForRootless(t *testing.T) {
+	opts := testOptions()
+	opts.Isolation = options.IsolationRootless
+	if err := verifyIsolationSupport(nil, "project", opts, testLogger()); err == nil {
+		t.Error("rootless isolation with the default image accepted")
+	}
+
+	opts.WorkspaceImage = "registry.example.com/devpod/workspace:latest"
+	if err := verifyIsolationSupport(nil, "project", opts, testLogger()); err != nil {
+		t.Errorf("rootless isolation with a prebuilt image rejected: %v", err)
+	}
+}
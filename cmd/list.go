@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	listOutput   string
+	listProjects []string
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the DevPod workspaces on Dokploy",
+	Long: `List the compose services created by this provider in the configured project.
+Use --project to list other projects as well; services not created by the provider are skipped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runList()
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "output format: table, json or yaml")
+	listCmd.Flags().StringSliceVar(&listProjects, "project", nil, "project(s) to list (default: DOKPLOY_PROJECT_NAME)")
+	rootCmd.AddCommand(listCmd)
+}
+
+// workspaceInfo describes a provider-owned workspace
+type workspaceInfo struct {
+	MachineID     string        `json:"machineId" yaml:"machineId"`
+	ComposeID     string        `json:"composeId" yaml:"composeId"`
+	Project       string        `json:"project" yaml:"project"`
+	Status        client.Status `json:"status" yaml:"status"`
+	DokployStatus string        `json:"dokployStatus" yaml:"dokployStatus"`
+	SSHHost       string        `json:"sshHost" yaml:"sshHost"`
+	SSHPort       int           `json:"sshPort,omitempty" yaml:"sshPort,omitempty"`
+	MachineType   string        `json:"machineType,omitempty" yaml:"machineType,omitempty"`
+	Image         string        `json:"image,omitempty" yaml:"image,omitempty"`
+	CreatedAt     string        `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	LastDeploy    string        `json:"lastDeploy,omitempty" yaml:"lastDeploy,omitempty"`
+}
+
+func runList() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	switch listOutput {
+	case "table", "json", "yaml":
+	default:
+		return fmt.Errorf("invalid output format '%s' (expected table, json or yaml)", listOutput)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	client := dokploy.NewClient(opts, logger)
+
+	projectNames := listProjects
+	if len(projectNames) == 0 {
+		projectNames = []string{opts.DokployProjectName}
+	}
+
+	workspaces, err := listWorkspaces(client, opts, projectNames, logger)
+	if err != nil {
+		return err
+	}
+
+	switch listOutput {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(workspaces)
+	case "yaml":
+		encoder := yaml.NewEncoder(os.Stdout)
+		encoder.SetIndent(2)
+		defer encoder.Close()
+		return encoder.Encode(workspaces)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "MACHINE\tCOMPOSE ID\tSTATUS\tSSH\tTYPE\tIMAGE\tCREATED\tLAST DEPLOY")
+	for _, workspace := range workspaces {
+		ssh := "-"
+		if workspace.SSHPort != 0 {
+			ssh = fmt.Sprintf("%s:%d", workspace.SSHHost, workspace.SSHPort)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			workspace.MachineID, workspace.ComposeID, workspace.Status, ssh,
+			orDash(workspace.MachineType), orDash(workspace.Image),
+			orDash(workspace.CreatedAt), orDash(workspace.LastDeploy))
+	}
+	return writer.Flush()
+}
+
+// listWorkspaces returns the provider-owned workspaces in the given projects. Projects that
+// do not exist are skipped.
+func listWorkspaces(client *dokploy.Client, opts *options.Options, projectNames []string, logger *logrus.Logger) ([]workspaceInfo, error) {
+	parsedURL, err := url.Parse(opts.DokployServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server URL: %w", err)
+	}
+	sshHost := strings.Split(parsedURL.Host, ":")[0]
+
+	projects, err := client.GetAllProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	workspaces := []workspaceInfo{}
+	for _, projectName := range projectNames {
+		found := false
+		for _, project := range projects {
+			if project.Name != projectName {
+				continue
+			}
+			found = true
+
+			for _, summary := range project.Composes {
+				compose, err := client.GetCompose(summary.ComposeID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get compose service %s: %w", summary.ComposeID, err)
+				}
+
+				marker, err := parseWorkspaceMarker(compose.ComposeFile)
+				if err != nil {
+					logger.Debugf("Skipping compose service %s: %v", compose.ComposeID, err)
+					continue
+				}
+				if !isProviderOwned(compose, compose.Name) {
+					logger.Debugf("Skipping compose service %s (%s): not created by the DevPod provider", compose.Name, compose.ComposeID)
+					continue
+				}
+
+				status, _ := dokploy.MapComposeStatus(compose.Status)
+				lastDeploy := ""
+				if deployment := compose.LatestDeployment(); deployment != nil {
+					lastDeploy = deployment.CreatedAt
+				}
+				workspace := workspaceInfo{
+					MachineID:     compose.Name,
+					ComposeID:     compose.ComposeID,
+					Project:       project.Name,
+					Status:        status,
+					DokployStatus: compose.Status,
+					SSHHost:       sshHost,
+					CreatedAt:     compose.CreatedAt,
+					LastDeploy:    lastDeploy,
+				}
+				if marker != nil {
+					if marker.SSHHost != "" {
+						workspace.SSHHost = marker.SSHHost
+					}
+					workspace.SSHPort = marker.SSHPort
+					workspace.MachineType = marker.MachineType
+					workspace.Image = marker.Image
+				}
+				workspaces = append(workspaces, workspace)
+			}
+		}
+		if !found {
+			logger.Warnf("Project '%s' does not exist, skipping", projectName)
+		}
+	}
+	return workspaces, nil
+}
+
+// orDash returns "-" for empty table cells
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
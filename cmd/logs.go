@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsDeployment bool
+	logsContainer  bool
+	logsFollow     bool
+	logsSince      string
+	logsTail       int
+	logsIdle       time.Duration
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the logs of a Dokploy workspace",
+	Long: `Show the logs of the workspace of the current machine (MACHINE_ID).
+
+By default the workspace container logs are shown, which include the setup script output.
+Use --deployment for the log of the latest Dokploy deployment, e.g. when the container
+never started.
+
+Without --follow the log ends once no new line arrived for --idle-timeout (default 5s,
+or 20s for workspaces on a remote server).`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLogs()
+	},
+}
+
+func init() {
+	logsCmd.Flags().BoolVar(&logsDeployment, "deployment", false, "show the log of the latest deployment")
+	logsCmd.Flags().BoolVar(&logsContainer, "container", false, "show the workspace container logs (default)")
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "keep streaming new log lines")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "only show container logs since this time (e.g. 10m or 2024-01-02T15:04:05Z)")
+	logsCmd.Flags().IntVar(&logsTail, "tail", 200, "number of container log lines to show from the end (0 for all)")
+	logsCmd.Flags().DurationVar(&logsIdle, "idle-timeout", 0, "end the log after this long without new lines when not following (default 5s, 20s on remote servers)")
+	logsCmd.MarkFlagsMutuallyExclusive("deployment", "container")
+	rootCmd.AddCommand(logsCmd)
+}
+
+func runLogs() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	machineID, err := getMachineIDFromContext()
+	if err != nil {
+		return fmt.Errorf("failed to get machine ID: %w", err)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	client := dokploy.NewClient(opts, logger)
+
+	composeBasic, err := client.GetComposeByName(machineID)
+	if err != nil {
+		return fmt.Errorf("workspace %s not found: %w", machineID, err)
+	}
+	compose, err := client.GetCompose(composeBasic.ComposeID)
+	if err != nil {
+		return fmt.Errorf("failed to get compose service: %w", err)
+	}
+
+	if logsDeployment {
+		deployment := compose.LatestDeployment()
+		if deployment == nil {
+			return fmt.Errorf("workspace %s has not been deployed yet", machineID)
+		}
+		logger.Debugf("Streaming deployment %s (%s, %s)", deployment.DeploymentID, deployment.Status, deployment.CreatedAt)
+		if err := client.StreamDeploymentLogs(deployment, compose.ServerID, logsFollow, logsIdle, os.Stdout); err != nil {
+			return fmt.Errorf("failed to get deployment logs: %w", err)
+		}
+		return nil
+	}
+
+	container, err := workspaceContainer(client, compose)
+	if err != nil {
+		return fmt.Errorf("%w; use --deployment to see why the deployment did not start it", err)
+	}
+	logger.Debugf("Streaming logs of container %s", container.Name)
+	if err := client.StreamContainerLogs(container.ContainerID, compose.ServerID, dokploy.ContainerLogsOptions{
+		Tail:        logsTail,
+		Since:       logsSince,
+		Follow:      logsFollow,
+		IdleTimeout: logsIdle,
+	}, os.Stdout); err != nil {
+		return fmt.Errorf("failed to get container logs: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,563 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	devpodconfig "github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/provider"
+	"github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// migratingSuffix marks the target compose service while source and target live in the same
+// Dokploy instance; the target takes over the machine's name once the source is deleted
+const migratingSuffix = "-migrating"
+
+// migratedSuffix marks the source compose service once the workspace moved; it is kept stopped,
+// with its volumes, until the user deletes it
+const migratedSuffix = "-migrated"
+
+// innerDockerScript prints what lives in the Docker daemon of a workspace and is not part of
+// /workspace: volumes, containers and locally built images, which have no registry digest
+const innerDockerScript = `set -eo pipefail
+if ! command -v docker >/dev/null 2>&1; then exit 0; fi
+docker info >/dev/null
+echo "volumes=$(docker volume ls -q | wc -l)"
+echo "containers=$(docker ps -aq | wc -l)"
+echo "local-images=$(docker image ls --format '{{.Repository}} {{.Digest}}' | awk '$1 != "<none>" && $2 == "<none>"' | wc -l)"`
+
+var (
+	migrateToServer  string
+	migrateToProfile string
+	migrateTimeout   time.Duration
+	migrateDelete    bool
+	migrateDiscard   bool
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move a workspace to another Dokploy server",
+	Long: `Move the workspace of the current machine (MACHINE_ID) to another server managed by Dokploy
+(--to-server <serverId>), or to another Dokploy instance (--to-profile <env file> with its own
+DOKPLOY_SERVER_URL and DOKPLOY_API_TOKEN).
+
+The equivalent compose service is created and deployed on the target with the same machine type,
+isolation, image and settings recorded in the x-devpod block (sidecars, networks, daemon options,
+hooks, inactivity), whatever the options of the target are; on another Dokploy instance the
+workspace uses the inactivity token of the profile. Workspaces created before settings were
+recorded are only migrated when the target options render the same settings. /workspace and the list of inner Docker images are streamed over SSH from
+the source to the target, where the images are pulled again, and the number of files and bytes
+restored is checked against the source. Once SSH works on the target, the machine state is
+updated with the new host and port, and the source is stopped and renamed to
+<machine>-migrated. It keeps its volumes until you delete it in Dokploy, or pass --delete-source
+to delete it right away. When anything fails before that, the target is deleted and the source
+is left as it was.
+
+Inner Docker volumes, containers and locally built images are not copied. The migration is
+refused when the source has any, unless --discard-docker-data is set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runMigrate()
+	},
+}
+
+func init() {
+	migrateCmd.Flags().StringVar(&migrateToServer, "to-server", "", "ID of the Dokploy server to move the workspace to")
+	migrateCmd.Flags().StringVar(&migrateToProfile, "to-profile", "", "env file with the options of another Dokploy instance (DOKPLOY_SERVER_URL, DOKPLOY_API_TOKEN, ...)")
+	migrateCmd.Flags().DurationVar(&migrateTimeout, "timeout", 0, "how long to wait for the target workspace (default: DOKPLOY_START_TIMEOUT)")
+	migrateCmd.Flags().BoolVar(&migrateDelete, "delete-source", false, "delete the source compose service and its volumes once the workspace moved, instead of keeping it stopped")
+	migrateCmd.Flags().BoolVar(&migrateDiscard, "discard-docker-data", false, "migrate even though inner Docker volumes, containers and locally built images are not copied")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate() (err error) {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	if migrateToServer == "" && migrateToProfile == "" {
+		return fmt.Errorf("--to-server or --to-profile is required")
+	}
+
+	machineID, err := getMachineIDFromContext()
+	if err != nil {
+		return fmt.Errorf("failed to get machine ID: %w", err)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	timeout := opts.StartTimeout
+	if migrateTimeout > 0 {
+		timeout = migrateTimeout
+	}
+
+	sourceClient := dokploy.NewClient(opts, logger)
+	projectID, err := findProjectID(sourceClient, opts.DokployProjectName)
+	if err != nil {
+		return err
+	}
+	if projectID == "" {
+		return fmt.Errorf("project '%s' not found", opts.DokployProjectName)
+	}
+	source, marker, err := findExistingWorkspace(sourceClient, projectID, machineID)
+	if err != nil {
+		return err
+	}
+	if source == nil {
+		return fmt.Errorf("workspace %s not found in project '%s'", machineID, opts.DokployProjectName)
+	}
+	if marker != nil && marker.Adopted {
+		return fmt.Errorf("workspace %s was adopted; its compose file is not rendered by the provider and cannot be migrated", machineID)
+	}
+
+	// The target uses the options of the profile, or the current ones
+	targetOpts := opts
+	if migrateToProfile != "" {
+		if targetOpts, err = loadProfile(migrateToProfile); err != nil {
+			return err
+		}
+	}
+	targetServerID := targetOpts.DokployServerID
+	if migrateToServer != "" {
+		targetServerID = migrateToServer
+	}
+	sameInstance := strings.TrimRight(targetOpts.DokployServerURL, "/") == strings.TrimRight(opts.DokployServerURL, "/")
+	if sameInstance && targetServerID == source.ServerID {
+		return fmt.Errorf("workspace %s already runs on this server", machineID)
+	}
+	targetClient := dokploy.NewClient(targetOpts, logger)
+
+	// SSH is published on the target server, or on the Dokploy host
+	parsedURL, err := url.Parse(targetOpts.DokployServerURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse server URL: %w", err)
+	}
+	targetHost := parsedURL.Hostname()
+	if targetServerID != "" {
+		server, err := targetClient.GetServer(targetServerID)
+		if err != nil {
+			return err
+		}
+		targetHost = server.IPAddress
+		logger.Infof("Target server: %s (%s)", server.Name, server.IPAddress)
+	}
+
+	// Render the workspace with the settings it was created with
+	renderOpts, recorded, err := migratedOptions(targetOpts, source, !sameInstance)
+	if err != nil {
+		return err
+	}
+	params, err := buildComposeParams(renderOpts, logger)
+	if err != nil {
+		return err
+	}
+	publicKey, err := composeSSHPublicKey(source, machineID, logger)
+	if err != nil {
+		return err
+	}
+	if !recorded {
+		// Compare with the source as it would be rendered in place
+		preview := params
+		preview.MachineID = machineID
+		preview.SSHPublicKey = publicKey
+		preview.ComposeID = source.ComposeID
+		previewContent, err := generateDockerCompose(preview, logger)
+		if err != nil {
+			return fmt.Errorf("failed to generate Docker Compose configuration: %w", err)
+		}
+		if err := checkSettingsPreserved(source.ComposeFile, previewContent); err != nil {
+			return fmt.Errorf("refusing to migrate workspace %s: %w", machineID, err)
+		}
+	}
+	machineFolder, err := findMachineFolder(machineID)
+	if err != nil {
+		return err
+	}
+	privateKey, err := ssh.GetPrivateKeyRawBase(machineFolder)
+	if err != nil {
+		return fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	// The source must run to be snapshotted
+	if status, _ := dokploy.MapComposeStatus(source.Status); status != client.StatusRunning {
+		logger.Infof("Starting workspace %s to copy its data...", machineID)
+		if err := sourceClient.StartCompose(source.ComposeID); err != nil {
+			return fmt.Errorf("failed to start Docker Compose service: %w", err)
+		}
+		if err := waitForWorkspaceReady(sourceClient, source.ComposeID, opts, timeout, logger); err != nil {
+			return fmt.Errorf("workspace %s did not become ready: %w", machineID, err)
+		}
+	}
+
+	// Inner Docker data is lost in the move
+	dockerState, err := inspectInnerDocker(source, opts, machineID, logger)
+	if err != nil {
+		return err
+	}
+	if lost := dockerState.uncopied(); len(lost) > 0 {
+		if !migrateDiscard {
+			return fmt.Errorf("workspace %s has %s in its Docker daemon, which are not copied; "+
+				"move them into /workspace or push the images, or pass --discard-docker-data", machineID, strings.Join(lost, ", "))
+		}
+		logger.Warnf("Discarding %s of the inner Docker daemon", strings.Join(lost, ", "))
+	}
+
+	targetProjectID, err := findProjectID(targetClient, targetOpts.DokployProjectName)
+	if err != nil {
+		return err
+	}
+	if targetProjectID == "" {
+		project, err := targetClient.CreateProject(dokploy.CreateProjectRequest{
+			Name:        targetOpts.DokployProjectName,
+			Description: "DevPod workspaces project - automatically created by Dokploy provider",
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create project: %w", err)
+		}
+		targetProjectID = project.ProjectID
+	}
+
+	sshPort, err := findAvailableSSHPort(targetClient, targetHost, logger)
+	if err != nil {
+		return err
+	}
+
+	targetName := machineID
+	if sameInstance {
+		targetName = machineID + migratingSuffix
+	}
+	logger.Infof("Creating compose service %s on the target...", targetName)
+	target, err := targetClient.CreateCompose(dokploy.CreateComposeRequest{
+		Name:        targetName,
+		Description: fmt.Sprintf("DevPod workspace migrated on %s via Docker Compose", time.Now().Format(time.RFC3339)),
+		ProjectID:   targetProjectID,
+		ComposeType: "docker-compose",
+		ServerID:    targetServerID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create Docker Compose service: %w", err)
+	}
+
+	// Until the target takes over the machine, a failed migration removes the target again
+	targetID := target.ComposeID
+	committed := false
+	defer func() {
+		if err != nil && !committed {
+			logger.Warnf("Migration failed, deleting the target compose service %s", targetID)
+			if deleteErr := targetClient.DeleteCompose(targetID, true); deleteErr != nil {
+				logger.Errorf("Failed to delete the target compose service %s: %v", targetID, deleteErr)
+			}
+		}
+	}()
+
+	params.MachineID = machineID
+	if targetServerID != "" {
+		params.SSHHost = targetHost
+	}
+	params.SSHPort = sshPort
+	params.SSHPublicKey = publicKey
+	params.ComposeID = target.ComposeID
+	dockerComposeContent, err := generateDockerCompose(params, logger)
+	if err != nil {
+		return fmt.Errorf("failed to generate Docker Compose configuration: %w", err)
+	}
+	if err := targetClient.SaveComposeFile(dokploy.SaveComposeFileRequest{
+		ComposeID:     target.ComposeID,
+		DockerCompose: dockerComposeContent,
+		Env:           inactivityComposeEnv(renderOpts),
+	}); err != nil {
+		return fmt.Errorf("failed to save Docker Compose file: %w", err)
+	}
+	if err := redeployCompose(targetClient, target, timeout, logger); err != nil {
+		return err
+	}
+	if err := waitForWorkspaceReady(targetClient, target.ComposeID, targetOpts, timeout, logger); err != nil {
+		return fmt.Errorf("target workspace did not become ready: %w", err)
+	}
+	if target, err = targetClient.GetCompose(target.ComposeID); err != nil {
+		return fmt.Errorf("failed to get compose service: %w", err)
+	}
+
+	// Stream /workspace from the source into the target
+	logger.Infof("Copying /workspace from %s to the target...", machineID)
+	if err := copyWorkspaceData(source, opts, target, targetOpts, machineID, logger); err != nil {
+		return err
+	}
+
+	address := net.JoinHostPort(targetHost, strconv.Itoa(sshPort))
+	if err := waitForSSHLogin(address, privateKey, timeout, logger); err != nil {
+		return err
+	}
+	logger.Infof("✓ Workspace answers SSH on %s", address)
+
+	// DevPod calls the provider with the target's options from now on
+	if migrateToProfile != "" {
+		if err := saveMachineOptions(machineFolder, map[string]string{
+			"DOKPLOY_SERVER_URL":   targetOpts.DokployServerURL,
+			"DOKPLOY_API_TOKEN":    targetOpts.DokployAPIToken,
+			"DOKPLOY_PROJECT_NAME": targetOpts.DokployProjectName,
+			"DOKPLOY_SERVER_ID":    targetServerID,
+		}); err != nil {
+			return err
+		}
+	}
+	committed = true
+
+	if migrateDelete {
+		logger.Infof("Deleting the source compose service %s...", source.ComposeID)
+		if err := sourceClient.DeleteCompose(source.ComposeID, !opts.RetainVolumesOnDelete); err != nil {
+			return fmt.Errorf("workspace copied to %s, but failed to delete the source compose service %s; delete it in Dokploy: %w", address, source.ComposeID, err)
+		}
+	} else {
+		// The source frees the machine's name but keeps its volumes until the user confirms
+		sourceName := machineID + migratedSuffix
+		logger.Infof("Stopping the source compose service %s...", source.ComposeID)
+		if err := sourceClient.StopCompose(source.ComposeID); err != nil {
+			return fmt.Errorf("workspace copied to %s, but failed to stop the source compose service %s; stop it in Dokploy: %w", address, source.ComposeID, err)
+		}
+		if err := sourceClient.RenameCompose(dokploy.RenameComposeRequest{ComposeID: source.ComposeID, Name: sourceName}); err != nil {
+			return fmt.Errorf("workspace copied to %s, but failed to rename the source compose service %s to %s; rename it in Dokploy: %w", address, source.ComposeID, sourceName, err)
+		}
+		logger.Infof("The source is kept stopped with its volumes as %s; delete it in Dokploy once the migrated workspace works", sourceName)
+	}
+
+	if targetName != machineID {
+		if err := targetClient.RenameCompose(dokploy.RenameComposeRequest{ComposeID: targetID, Name: machineID}); err != nil {
+			return fmt.Errorf("failed to rename compose service %s to %s; rename it in Dokploy: %w", targetID, machineID, err)
+		}
+	}
+
+	logger.Infof("✓ Workspace %s migrated to %s", machineID, address)
+	return nil
+}
+
+// migratedOptions returns the options the target of a migration is rendered with: the connection
+// options of targetOpts with the settings the source workspace was created with. On another
+// Dokploy instance the workspace stops itself with the inactivity token of that instance.
+func migratedOptions(targetOpts *options.Options, source *dokploy.Compose, otherInstance bool) (*options.Options, bool, error) {
+	renderOpts, recorded, err := workspaceOptions(targetOpts, source)
+	if err != nil {
+		return nil, false, err
+	}
+	if otherInstance {
+		renderOpts.InactivityAPIToken = targetOpts.InactivityAPIToken
+	}
+	return renderOpts, recorded, nil
+}
+
+// innerDockerState counts what the inner Docker daemon of a workspace holds outside /workspace
+type innerDockerState struct {
+	Volumes     int
+	Containers  int
+	LocalImages int
+}
+
+// inspectInnerDocker reports the inner Docker data of a running workspace
+func inspectInnerDocker(compose *dokploy.Compose, opts *options.Options, machineID string, logger *logrus.Logger) (*innerDockerState, error) {
+	sshClient, err := connectComposeSSH(compose, opts, machineID, logger)
+	if err != nil {
+		return nil, err
+	}
+	defer sshClient.Close()
+
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+	session.Stderr = os.Stderr
+
+	output, err := session.Output(bashCommand(innerDockerScript))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect the Docker daemon of workspace %s: %w", machineID, err)
+	}
+	return parseInnerDockerState(string(output))
+}
+
+// parseInnerDockerState parses the key=count lines of innerDockerScript
+func parseInnerDockerState(output string) (*innerDockerState, error) {
+	state := &innerDockerState{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, found := strings.Cut(line, "=")
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil {
+			return nil, fmt.Errorf("unexpected output of the Docker inspection: %s", line)
+		}
+		switch key {
+		case "volumes":
+			state.Volumes = count
+		case "containers":
+			state.Containers = count
+		case "local-images":
+			state.LocalImages = count
+		}
+	}
+	return state, nil
+}
+
+// uncopied describes the inner Docker data a migration leaves behind
+func (s *innerDockerState) uncopied() []string {
+	var lost []string
+	for _, item := range []struct {
+		count int
+		name  string
+	}{
+		{s.Volumes, "volume"},
+		{s.Containers, "container"},
+		{s.LocalImages, "locally built image"},
+	} {
+		switch {
+		case item.count == 1:
+			lost = append(lost, "1 "+item.name)
+		case item.count > 1:
+			lost = append(lost, fmt.Sprintf("%d %ss", item.count, item.name))
+		}
+	}
+	return lost
+}
+
+// copyWorkspaceData pipes a snapshot of the source workspace into the target workspace over SSH,
+// pulling the inner Docker images of the source on the target
+func copyWorkspaceData(source *dokploy.Compose, sourceOpts *options.Options, target *dokploy.Compose, targetOpts *options.Options, machineID string, logger *logrus.Logger) error {
+	sourceSSH, err := connectComposeSSH(source, sourceOpts, machineID, logger)
+	if err != nil {
+		return err
+	}
+	defer sourceSSH.Close()
+	targetSSH, err := connectComposeSSH(target, targetOpts, machineID, logger)
+	if err != nil {
+		return err
+	}
+	defer targetSSH.Close()
+
+	sourceSession, err := sourceSSH.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer sourceSession.Close()
+	targetSession, err := targetSSH.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer targetSession.Close()
+
+	reader, writer := io.Pipe()
+	sourceSession.Stdout = writer
+	sourceSession.Stderr = os.Stderr
+	targetSession.Stdin = reader
+	targetSession.Stdout = os.Stderr
+	targetSession.Stderr = os.Stderr
+
+	snapshotDone := make(chan error, 1)
+	go func() {
+		err := sourceSession.Run(snapshotScript(true))
+		writer.CloseWithError(err)
+		snapshotDone <- err
+	}()
+
+	restoreErr := targetSession.Run(restoreScript(true, true))
+	reader.CloseWithError(restoreErr)
+	if err := <-snapshotDone; err != nil {
+		return fmt.Errorf("failed to snapshot the source workspace: %w", err)
+	}
+	if restoreErr != nil {
+		return fmt.Errorf("failed to restore into the target workspace: %w", restoreErr)
+	}
+	return nil
+}
+
+// loadProfile loads the options of another Dokploy instance from an env file of KEY=value lines.
+// The variables of the file override the environment, which is left unchanged.
+func loadProfile(path string) (*options.Options, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open profile: %w", err)
+	}
+	defer file.Close()
+
+	values := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid line in profile %s: %s", path, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	opts, err := options.Load(func(key string) string {
+		if value, found := values[key]; found {
+			return value
+		}
+		return os.Getenv(key)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load profile %s: %w", path, err)
+	}
+	return opts, nil
+}
+
+// saveMachineOptions sets provider options of a DevPod machine in its machine.json, overriding
+// the provider's options for this machine
+func saveMachineOptions(machineFolder string, values map[string]string) error {
+	machineFile := filepath.Join(machineFolder, provider.MachineConfigFile)
+	data, err := os.ReadFile(machineFile)
+	if err != nil {
+		return fmt.Errorf("failed to read DevPod machine state: %w", err)
+	}
+
+	var machine provider.Machine
+	if err := json.Unmarshal(data, &machine); err != nil {
+		return fmt.Errorf("failed to parse DevPod machine state: %w", err)
+	}
+	if machine.Provider.Options == nil {
+		machine.Provider.Options = map[string]devpodconfig.OptionValue{}
+	}
+	for name, value := range values {
+		machine.Provider.Options[name] = devpodconfig.OptionValue{Value: value, UserProvided: true}
+	}
+
+	data, err = json.Marshal(machine)
+	if err != nil {
+		return fmt.Errorf("failed to encode DevPod machine state: %w", err)
+	}
+	if err := os.WriteFile(machineFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to save DevPod machine state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+)
+
+func TestLoadProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		profile     string
+		wantURL     string
+		wantToken   string
+		wantProject string
+		wantServer  string
+		wantErr     bool
+	}{
+		{
+			name:        "plain values",
+			profile:     "DOKPLOY_SERVER_URL=https://other.example.com\nDOKPLOY_API_TOKEN=secret\n",
+			wantURL:     "https://other.example.com",
+			wantToken:   "secret",
+			wantProject: "current-project",
+		},
+		{
+			name: "comments, exports and quotes",
+			profile: `# other Dokploy instance
+export DOKPLOY_SERVER_URL="https://other.example.com"
+
+DOKPLOY_API_TOKEN='secret=value'
+DOKPLOY_PROJECT_NAME = workspaces
+DOKPLOY_SERVER_ID=server-1
+`,
+			wantURL:     "https://other.example.com",
+			wantToken:   "secret=value",
+			wantProject: "workspaces",
+			wantServer:  "server-1",
+		},
+		{
+			name:        "file overrides the environment",
+			profile:     "DOKPLOY_API_TOKEN=profile-token\n",
+			wantURL:     "https://current.example.com",
+			wantToken:   "profile-token",
+			wantProject: "current-project",
+		},
+		{name: "line without value", profile: "DOKPLOY_SERVER_URL\n", wantErr: true},
+		{name: "invalid options", profile: "DOKPLOY_SERVER_URL=\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOKPLOY_SERVER_URL", "https://current.example.com")
+			t.Setenv("DOKPLOY_API_TOKEN", "current-token")
+			t.Setenv("DOKPLOY_PROJECT_NAME", "current-project")
+			t.Setenv("DOKPLOY_SERVER_ID", "")
+
+			path := filepath.Join(t.TempDir(), "profile.env")
+			if err := os.WriteFile(path, []byte(tt.profile), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			opts, err := loadProfile(path)
+			if token := os.Getenv("DOKPLOY_API_TOKEN"); token != "current-token" {
+				t.Errorf("loadProfile() changed the environment: DOKPLOY_API_TOKEN = %s", token)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadProfile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if opts.DokployServerURL != tt.wantURL || opts.DokployAPIToken != tt.wantToken ||
+				opts.DokployProjectName != tt.wantProject || opts.DokployServerID != tt.wantServer {
+				t.Errorf("loadProfile() = %s, %s, %s, %s, want %s, %s, %s, %s",
+					opts.DokployServerURL, opts.DokployAPIToken, opts.DokployProjectName, opts.DokployServerID,
+					tt.wantURL, tt.wantToken, tt.wantProject, tt.wantServer)
+			}
+		})
+	}
+}
+
+func TestMigratedOptions(t *testing.T) {
+	created := testOptions()
+	created.Sidecars = "services:\n  redis:\n    image: redis:7\n"
+	created.InactivityTimeout = time.Hour
+	created.InactivityAPIToken = "source-token"
+	source := &dokploy.Compose{ComposeFile: renderTestCompose(t, created), Env: "DEVPOD_INACTIVITY_TOKEN=source-token"}
+
+	target := testOptions()
+	target.DokployServerURL = "https://other.example.com"
+	target.InactivityAPIToken = "target-token"
+
+	tests := []struct {
+		name          string
+		otherInstance bool
+		wantToken     string
+	}{
+		{name: "same instance", wantToken: "source-token"},
+		{name: "other instance", otherInstance: true, wantToken: "target-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, recorded, err := migratedOptions(target, source, tt.otherInstance)
+			if err != nil {
+				t.Fatalf("migratedOptions() error = %v", err)
+			}
+			if !recorded {
+				t.Error("migratedOptions() recorded = false")
+			}
+			if got.DokployServerURL != target.DokployServerURL {
+				t.Errorf("migratedOptions() server URL = %s, want %s", got.DokployServerURL, target.DokployServerURL)
+			}
+			if got.Sidecars != strings.TrimSpace(created.Sidecars) || got.InactivityTimeout != created.InactivityTimeout {
+				t.Errorf("migratedOptions() = sidecars %q, inactivity %v, want the source settings", got.Sidecars, got.InactivityTimeout)
+			}
+			if got.InactivityAPIToken != tt.wantToken {
+				t.Errorf("migratedOptions() token = %s, want %s", got.InactivityAPIToken, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestLoadProfileMissingFile(t *testing.T) {
+	if _, err := loadProfile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("loadProfile() of a missing file succeeded")
+	}
+}
+
+func TestParseInnerDockerState(t *testing.T) {
+	tests := []struct {
+		name         string
+		output       string
+		want         innerDockerState
+		wantUncopied []string
+		wantErr      bool
+	}{
+		{name: "no Docker", output: ""},
+		{name: "empty daemon", output: "volumes=0\ncontainers=0\nlocal-images=0\n"},
+		{
+			name:         "data to lose",
+			output:       "volumes=2\ncontainers=1\nlocal-images=3\n",
+			want:         innerDockerState{Volumes: 2, Containers: 1, LocalImages: 3},
+			wantUncopied: []string{"2 volumes", "1 container", "3 locally built images"},
+		},
+		{
+			name:         "padded counts",
+			output:       "volumes=       1\ncontainers=0\nlocal-images=0\n",
+			want:         innerDockerState{Volumes: 1},
+			wantUncopied: []string{"1 volume"},
+		},
+		{name: "unexpected line", output: "Cannot connect to the Docker daemon\n", wantErr: true},
+		{name: "invalid count", output: "volumes=many\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseInnerDockerState(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseInnerDockerState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *got != tt.want {
+				t.Errorf("parseInnerDockerState() = %+v, want %+v", *got, tt.want)
+			}
+			if uncopied := got.uncopied(); !reflect.DeepEqual(uncopied, tt.wantUncopied) {
+				t.Errorf("uncopied() = %v, want %v", uncopied, tt.wantUncopied)
+			}
+		})
+	}
+}
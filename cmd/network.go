@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dokployNetwork is the shared external network Dokploy attaches its apps and Traefik to
+const dokployNetwork = "dokploy-network"
+
+// proxyImage is the image used to forward traffic from the workspace network to allowed Dokploy services
+const proxyImage = "alpine/socat:latest"
+
+// allowedService is a Dokploy service the workspace may reach through a proxy
+type allowedService struct {
+	Name  string
+	Ports []int
+}
+
+// parseAllowedServices parses DOKPLOY_ALLOWED_SERVICES entries of the form name:port.
+// Several ports for the same service are given as separate entries.
+func parseAllowedServices(entries []string) ([]allowedService, error) {
+	byName := make(map[string]*allowedService)
+	for _, entry := range entries {
+		name, portValue, found := strings.Cut(entry, ":")
+		if !found || name == "" {
+			return nil, fmt.Errorf("allowed service '%s' must have the form name:port", entry)
+		}
+		port, err := strconv.Atoi(portValue)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("allowed service '%s' has an invalid port", entry)
+		}
+		if byName[name] == nil {
+			byName[name] = &allowedService{Name: name}
+		}
+		byName[name].Ports = append(byName[name].Ports, port)
+	}
+
+	services := make([]allowedService, 0, len(byName))
+	for _, service := range byName {
+		services = append(services, *service)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
+
+// allowedServiceProxies returns one socat proxy service per allowed Dokploy service. The proxy
+// joins dokploy-network and the private workspace network, where it answers to the service's
+// name, so the workspace reaches exactly those services without joining dokploy-network itself.
+func allowedServiceProxies(services []allowedService) map[string]map[string]interface{} {
+	proxies := make(map[string]map[string]interface{}, len(services))
+	for _, service := range services {
+		var forwards []string
+		for _, port := range service.Ports {
+			forwards = append(forwards, fmt.Sprintf("socat TCP-LISTEN:%d,fork,reuseaddr TCP:%s:%d &", port, service.Name, port))
+		}
+
+		proxies["proxy-"+service.Name] = map[string]interface{}{
+			"image":      proxyImage,
+			"restart":    "unless-stopped",
+			"entrypoint": []string{"/bin/sh", "-c"},
+			"command":    []string{strings.Join(forwards, " ") + " wait"},
+			"networks": map[string]interface{}{
+				dokployNetwork: map[string]interface{}{},
+				workspaceNetwork: map[string]interface{}{
+					"aliases": []string{service.Name},
+				},
+			},
+		}
+	}
+	return proxies
+}
+
+// networkComposeLines returns the extra workspace network entries and top-level network
+// definitions for the workspace networking options
+func networkComposeLines(joinDokployNetwork bool, allowed []allowedService) (workspaceNetworks, networks []string) {
+	if joinDokployNetwork {
+		workspaceNetworks = []string{"- " + dokployNetwork}
+	}
+	if joinDokployNetwork || len(allowed) > 0 {
+		networks = []string{
+			dokployNetwork + ":",
+			"  external: true",
+		}
+	}
+	return workspaceNetworks, networks
+}
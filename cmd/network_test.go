@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAllowedServices(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []allowedService
+		wantErr bool
+	}{
+		{name: "none", want: []allowedService{}},
+		{
+			name:    "sorted and grouped by name",
+			entries: []string{"redis:6379", "postgres:5432", "redis:6380"},
+			want: []allowedService{
+				{Name: "postgres", Ports: []int{5432}},
+				{Name: "redis", Ports: []int{6379, 6380}},
+			},
+		},
+		{name: "missing port", entries: []string{"postgres"}, wantErr: true},
+		{name: "missing name", entries: []string{":5432"}, wantErr: true},
+		{name: "port not a number", entries: []string{"postgres:pg"}, wantErr: true},
+		{name: "port out of range", entries: []string{"postgres:70000"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAllowedServices(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAllowedServices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseAllowedServices() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNetworkComposeLines(t *testing.T) {
+	tests := []struct {
+		name              string
+		join              bool
+		allowed           []allowedService
+		wantWorkspaceNets []string
+		wantNetworks      []string
+	}{
+		{name: "private only"},
+		{
+			name:              "join dokploy-network",
+			join:              true,
+			wantWorkspaceNets: []string{"- dokploy-network"},
+			wantNetworks:      []string{"dokploy-network:", "  external: true"},
+		},
+		{
+			name:         "proxies only",
+			allowed:      []allowedService{{Name: "postgres", Ports: []int{5432}}},
+			wantNetworks: []string{"dokploy-network:", "  external: true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceNets, networks := networkComposeLines(tt.join, tt.allowed)
+			if !reflect.DeepEqual(workspaceNets, tt.wantWorkspaceNets) || !reflect.DeepEqual(networks, tt.wantNetworks) {
+				t.Errorf("networkComposeLines() = %q, %q, want %q, %q", workspaceNets, networks, tt.wantWorkspaceNets, tt.wantNetworks)
+			}
+		})
+	}
+}
+
+func TestAllowedServiceProxies(t *testing.T) {
+	proxies := allowedServiceProxies([]allowedService{{Name: "postgres", Ports: []int{5432, 5433}}})
+	proxy, ok := proxies["proxy-postgres"]
+	if !ok {
+		t.Fatalf("proxy-postgres missing: %v", proxies)
+	}
+	wantCommand := []string{"socat TCP-LISTEN:5432,fork,reuseaddr TCP:postgres:5432 & socat TCP-LISTEN:5433,fork,reuseaddr TCP:postgres:5433 & wait"}
+	if !reflect.DeepEqual(proxy["command"], wantCommand) {
+		t.Errorf("command = %q, want %q", proxy["command"], wantCommand)
+	}
+	networks := proxy["networks"].(map[string]interface{})
+	aliases := networks[workspaceNetwork].(map[string]interface{})["aliases"]
+	if !reflect.DeepEqual(aliases, []string{"postgres"}) {
+		t.Errorf("workspace network aliases = %v, want [postgres]", aliases)
+	}
+}
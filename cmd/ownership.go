@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"gopkg.in/yaml.v3"
+)
+
+// providerMarker identifies compose services managed by this provider in the x-devpod block
+const providerMarker = "dokploy-devpod-provider"
+
+// legacyDescriptionPrefix starts the description of workspaces created before the x-devpod marker existed
+const legacyDescriptionPrefix = "DevPod workspace created on"
+
+// templateVersion is stamped into every generated compose file; bump it whenever the templates
+// change so that upgrade can find workspaces rendered from an older version
+const templateVersion = 2
+
+// workspaceMarker is the x-devpod block the provider writes into every workspace compose file
+type workspaceMarker struct {
+	ManagedBy string `yaml:"managed-by"`
+	MachineID string `yaml:"machine-id"`
+	SSHPort   int    `yaml:"ssh-port"`
+	// SSHHost is set for workspaces published on another host than DOKPLOY_SERVER_URL
+	SSHHost     string `yaml:"ssh-host,omitempty"`
+	MachineType string `yaml:"machine-type,omitempty"`
+	Isolation   string `yaml:"isolation,omitempty"`
+	Image       string `yaml:"image,omitempty"`
+	// TemplateVersion is 0 for workspaces created before versions were stamped
+	TemplateVersion int `yaml:"template-version,omitempty"`
+	// Settings is nil for workspaces created before their settings were recorded
+	Settings *workspaceSettings `yaml:"settings,omitempty"`
+	// Adopted services keep their own compose file; Service is the one running sshd
+	Adopted bool   `yaml:"adopted,omitempty"`
+	Service string `yaml:"service,omitempty"`
+}
+
+// parseWorkspaceMarker reads the x-devpod block of a compose file. It returns nil when the
+// file has no marker.
+func parseWorkspaceMarker(composeFile string) (*workspaceMarker, error) {
+	if strings.TrimSpace(composeFile) == "" {
+		return nil, nil
+	}
+
+	var document struct {
+		Marker *workspaceMarker `yaml:"x-devpod"`
+	}
+	if err := yaml.Unmarshal([]byte(composeFile), &document); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file: %w", err)
+	}
+	return document.Marker, nil
+}
+
+// isProviderOwned reports whether a compose service (with its compose file loaded) was created
+// by this provider for the given machine
+func isProviderOwned(compose *dokploy.Compose, machineID string) bool {
+	marker, err := parseWorkspaceMarker(compose.ComposeFile)
+	if err == nil && marker != nil {
+		return marker.ManagedBy == providerMarker && marker.MachineID == machineID
+	}
+	return strings.HasPrefix(compose.Description, legacyDescriptionPrefix)
+}
+
+// findExistingWorkspace looks for a compose service named after the machine in the project.
+// It returns the provider-owned service with its compose file loaded, nil when there is none,
+// and an error when a service with that name exists but was not created by this provider.
+func findExistingWorkspace(client *dokploy.Client, projectID, machineID string) (*dokploy.Compose, *workspaceMarker, error) {
+	matches, err := client.FindComposesInProject(projectID, machineID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var foreign *dokploy.Compose
+	for _, match := range matches {
+		compose, err := client.GetCompose(match.ComposeID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get compose service %s: %w", match.ComposeID, err)
+		}
+		if !isProviderOwned(compose, machineID) {
+			foreign = compose
+			continue
+		}
+
+		marker, err := parseWorkspaceMarker(compose.ComposeFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return compose, marker, nil
+	}
+
+	if foreign != nil {
+		return nil, nil, fmt.Errorf("compose service '%s' (%s) already exists in the project but was not created by the DevPod provider; "+
+			"rename or delete it, or use a different workspace name", machineID, foreign.ComposeID)
+	}
+	return nil, nil, nil
+}
+
+// workspaceContainer returns the container running sshd: devpod-workspace, or the service
+// recorded in the marker of an adopted compose service
+func workspaceContainer(client *dokploy.Client, compose *dokploy.Compose) (*dokploy.Container, error) {
+	if marker, err := parseWorkspaceMarker(compose.ComposeFile); err == nil && marker != nil && marker.Service != "" {
+		return client.GetServiceContainer(compose, marker.Service)
+	}
+	return client.GetWorkspaceContainer(compose)
+}
+
+// workspaceSSHHost returns the host a workspace publishes SSH on: the ssh-host of its marker
+// (e.g. a remote Dokploy server it was migrated to), otherwise the host of DOKPLOY_SERVER_URL
+func workspaceSSHHost(compose *dokploy.Compose, opts *options.Options) string {
+	if marker, err := parseWorkspaceMarker(compose.ComposeFile); err == nil && marker != nil && marker.SSHHost != "" {
+		return marker.SSHHost
+	}
+	parsedURL, err := url.Parse(opts.DokployServerURL)
+	if err != nil {
+		return ""
+	}
+	return parsedURL.Hostname()
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+)
+
+func TestParseWorkspaceMarker(t *testing.T) {
+	tests := []struct {
+		name        string
+		composeFile string
+		want        *workspaceMarker
+		wantErr     bool
+	}{
+		{name: "empty file", composeFile: "  \n"},
+		{name: "no marker", composeFile: "services:\n  app:\n    image: nginx\n"},
+		{
+			name:        "marker",
+			composeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n  ssh-port: 2223\n  machine-type: medium\n  template-version: 1\nservices: {}\n",
+			want:        &workspaceMarker{ManagedBy: providerMarker, MachineID: "ws", SSHPort: 2223, MachineType: "medium", TemplateVersion: 1},
+		},
+		{
+			name:        "adopted marker",
+			composeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: app\n  ssh-port: 2200\n  ssh-host: 10.0.0.5\n  adopted: true\n  service: app\n",
+			want:        &workspaceMarker{ManagedBy: providerMarker, MachineID: "app", SSHPort: 2200, SSHHost: "10.0.0.5", Adopted: true, Service: "app"},
+		},
+		{name: "invalid YAML", composeFile: "x-devpod: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWorkspaceMarker(tt.composeFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWorkspaceMarker() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			switch {
+			case tt.want == nil && got != nil:
+				t.Errorf("parseWorkspaceMarker() = %+v, want nil", got)
+			case tt.want != nil && (got == nil || *got != *tt.want):
+				t.Errorf("parseWorkspaceMarker() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsProviderOwned(t *testing.T) {
+	const markerFile = "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n"
+
+	tests := []struct {
+		name    string
+		compose dokploy.Compose
+		want    bool
+	}{
+		{name: "marker for the machine", compose: dokploy.Compose{ComposeFile: markerFile}, want: true},
+		{name: "marker for another machine", compose: dokploy.Compose{ComposeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: other\n"}},
+		{name: "marker of another tool", compose: dokploy.Compose{ComposeFile: "x-devpod:\n  managed-by: someone-else\n  machine-id: ws\n"}},
+		{
+			name:    "marker wins over a legacy description",
+			compose: dokploy.Compose{ComposeFile: "x-devpod:\n  managed-by: someone-else\n  machine-id: ws\n", Description: legacyDescriptionPrefix + " 2024-01-01"},
+		},
+		{name: "legacy workspace", compose: dokploy.Compose{ComposeFile: "services: {}\n", Description: legacyDescriptionPrefix + " 2024-01-01"}, want: true},
+		{name: "foreign service", compose: dokploy.Compose{ComposeFile: "services: {}\n", Description: "my app"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isProviderOwned(&tt.compose, "ws"); got != tt.want {
+				t.Errorf("isProviderOwned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceSSHHost(t *testing.T) {
+	opts := testOptions()
+	opts.DokployServerURL = "https://dokploy.example.com:3000"
+
+	if got := workspaceSSHHost(&dokploy.Compose{ComposeFile: "services: {}\n"}, opts); got != "dokploy.example.com" {
+		t.Errorf("workspaceSSHHost() = %q, want the Dokploy host", got)
+	}
+	marked := &dokploy.Compose{ComposeFile: "x-devpod:\n  ssh-host: 10.0.0.5\n"}
+	if got := workspaceSSHHost(marked, opts); got != "10.0.0.5" {
+		t.Errorf("workspaceSSHHost() = %q, want the marker's ssh-host", got)
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// setupWaitTimeout bounds how long create follows the setup progress before leaving the rest to status
+const setupWaitTimeout = 5 * time.Minute
+
+// waitPollInterval is the delay between two checks while waiting for a workspace state
+const waitPollInterval = 5 * time.Second
+
+// errWaitTimeout is returned (wrapped with diagnostics) when a workspace does not reach the target state in time
+var errWaitTimeout = errors.New("timed out waiting for the workspace")
+
+// waitForWorkspaceReady follows the setup progress of a workspace through its healthcheck until the
+// container is healthy and SSH answers, the setup fails or the timeout expires
+func waitForWorkspaceReady(dokployClient *dokploy.Client, composeID string, opts *options.Options, timeout time.Duration, logger *logrus.Logger) error {
+	deadline := time.Now().Add(timeout)
+	start := time.Now()
+	var last, diagnosis string
+
+	for time.Now().Before(deadline) {
+		compose, err := dokployClient.GetCompose(composeID)
+		if err != nil {
+			diagnosis = fmt.Sprintf("failed to get compose service: %v", err)
+			logger.Debug(diagnosis)
+			time.Sleep(waitPollInterval)
+			continue
+		}
+
+		health, progress, err := getWorkspaceHealth(dokployClient, compose, logger)
+		switch {
+		case err != nil:
+			diagnosis = fmt.Sprintf("compose status %s, workspace container not available: %v", compose.Status, err)
+			logger.Debugf("Workspace container not available yet: %v", err)
+		case progress != nil && progress.Failed():
+			return progress.Err()
+		case progress != nil:
+			diagnosis = fmt.Sprintf("setup still running: %s", progress)
+			if current := progress.String(); current != last {
+				logger.Infof("   Setup: %s (%v elapsed)", current, time.Since(start).Round(time.Second))
+				last = current
+			}
+		case health == client.HealthUnhealthy:
+			return fmt.Errorf("workspace container is unhealthy; check the container logs in the Dokploy dashboard")
+		case health == client.HealthHealthy, health == client.HealthNone:
+			// Workspaces without a healthcheck are only judged by SSH
+			if workspaceSSHReady(compose, opts, logger) {
+				logger.Infof("✓ Workspace is ready for SSH (%v elapsed)", time.Since(start).Round(time.Second))
+				return nil
+			}
+			diagnosis = fmt.Sprintf("container health %s, SSH not answering yet", health)
+		default:
+			diagnosis = fmt.Sprintf("container health %s", health)
+		}
+
+		time.Sleep(waitPollInterval)
+	}
+
+	if diagnosis == "" {
+		diagnosis = "no state observed"
+	}
+	return fmt.Errorf("%w to become ready after %v (last state: %s)", errWaitTimeout, timeout, diagnosis)
+}
+
+// waitForDeployment waits until a deployment newer than previousID has finished
+func waitForDeployment(dokployClient *dokploy.Client, composeID, previousID string, timeout time.Duration, logger *logrus.Logger) error {
+	deadline := time.Now().Add(timeout)
+	start := time.Now()
+	var last, diagnosis string
+
+	for time.Now().Before(deadline) {
+		compose, err := dokployClient.GetCompose(composeID)
+		if err != nil {
+			diagnosis = fmt.Sprintf("failed to get compose service: %v", err)
+			logger.Debug(diagnosis)
+			time.Sleep(waitPollInterval)
+			continue
+		}
+
+		deployment := compose.LatestDeployment()
+		switch {
+		case deployment == nil || deployment.DeploymentID == previousID:
+			diagnosis = "deployment queued"
+		case deployment.Status == "done":
+			logger.Infof("✓ Deployment completed (%v elapsed)", time.Since(start).Round(time.Second))
+			return nil
+		case deployment.Status == "error":
+			return fmt.Errorf("deployment %s failed; run `dokploy-provider logs --deployment` for details", deployment.DeploymentID)
+		default:
+			diagnosis = fmt.Sprintf("deployment %s", deployment.Status)
+		}
+
+		if diagnosis != last {
+			logger.Infof("   Waiting: %s", diagnosis)
+			last = diagnosis
+		}
+		time.Sleep(waitPollInterval)
+	}
+
+	if diagnosis == "" {
+		diagnosis = "no state observed"
+	}
+	return fmt.Errorf("%w deployment after %v (last state: %s)", errWaitTimeout, timeout, diagnosis)
+}
+
+// waitForWorkspaceStopped waits until the workspace container is no longer running
+func waitForWorkspaceStopped(dokployClient *dokploy.Client, composeID string, timeout time.Duration, logger *logrus.Logger) error {
+	deadline := time.Now().Add(timeout)
+	start := time.Now()
+	var last, diagnosis string
+
+	for time.Now().Before(deadline) {
+		compose, err := dokployClient.GetCompose(composeID)
+		if err != nil {
+			diagnosis = fmt.Sprintf("failed to get compose service: %v", err)
+			logger.Debug(diagnosis)
+			time.Sleep(waitPollInterval)
+			continue
+		}
+
+		container, err := workspaceContainer(dokployClient, compose)
+		if err != nil {
+			// Stopped compose services may have no container left at all
+			if errors.Is(err, dokploy.ErrNotFound) {
+				logger.Infof("✓ Workspace container is down (%v elapsed)", time.Since(start).Round(time.Second))
+				return nil
+			}
+			diagnosis = fmt.Sprintf("compose status %s, failed to list containers: %v", compose.Status, err)
+			logger.Debug(diagnosis)
+			time.Sleep(waitPollInterval)
+			continue
+		}
+
+		inspect, err := dokployClient.InspectContainer(container.ContainerID, compose.ServerID)
+		if err != nil {
+			diagnosis = fmt.Sprintf("compose status %s, failed to inspect container %s: %v", compose.Status, container.Name, err)
+			logger.Debug(diagnosis)
+			time.Sleep(waitPollInterval)
+			continue
+		}
+
+		switch inspect.State.Status {
+		case "exited", "created", "dead":
+			logger.Infof("✓ Workspace container is down (%s, %v elapsed)", inspect.State.Status, time.Since(start).Round(time.Second))
+			return nil
+		}
+
+		diagnosis = fmt.Sprintf("compose status %s, container %s is %s", compose.Status, container.Name, inspect.State.Status)
+		if diagnosis != last {
+			logger.Infof("   Waiting: %s", diagnosis)
+			last = diagnosis
+		}
+		time.Sleep(waitPollInterval)
+	}
+
+	if diagnosis == "" {
+		diagnosis = "no state observed"
+	}
+	return fmt.Errorf("%w to stop after %v (last state: %s)", errWaitTimeout, timeout, diagnosis)
+}
+
+// workspaceSSHReady reports whether the workspace answers SSH on its published port
+func workspaceSSHReady(compose *dokploy.Compose, opts *options.Options, logger *logrus.Logger) bool {
+	sshPort, err := extractSSHPortFromCompose(compose, opts, logger)
+	if err != nil || sshPort == 0 {
+		logger.Debugf("SSH port not known yet: %v", err)
+		return false
+	}
+
+	return checkSSHReadiness(workspaceSSHHost(compose, opts), sshPort, logger)
+}
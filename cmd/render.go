@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Stand-ins for values only known once create runs against Dokploy
+const (
+	renderComposeID = "<compose-id>"
+	renderPublicKey = "<public key of the DevPod machine>"
+)
+
+var (
+	renderSSHPort     int
+	renderComposeOnly bool
+)
+
+// renderCmd represents the render command
+var renderCmd = &cobra.Command{
+	Use:   "render",
+	Short: "Print the compose file create would deploy, without contacting Dokploy",
+	Long: `Render the workspace of the current machine (MACHINE_ID, default "devpod-render") from the
+current options and templates, without contacting Dokploy. The output lists the resolved options,
+the SSH port, the Dokploy API calls create would make and the compose file.
+
+Values only known to Dokploy are stand-ins: the SSH port is --ssh-port and the compose ID is
+"<compose-id>". The SSH public key is read from MACHINE_FOLDER when set.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRender()
+	},
+}
+
+func init() {
+	renderCmd.Flags().IntVar(&renderSSHPort, "ssh-port", sshPortRangeStart, "SSH port to render")
+	renderCmd.Flags().BoolVar(&renderComposeOnly, "compose-only", false, "only print the compose file")
+	rootCmd.AddCommand(renderCmd)
+}
+
+func runRender() error {
+	// Setup logger; render output goes to stdout, progress messages only when verbose
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	// Render never calls the API, so it does not need a token
+	if os.Getenv("DOKPLOY_API_TOKEN") == "" {
+		os.Setenv("DOKPLOY_API_TOKEN", "unused")
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	machineID := opts.MachineID
+	if machineID == "" {
+		machineID = "devpod-render"
+	}
+
+	params, err := buildComposeParams(opts, logger)
+	if err != nil {
+		return err
+	}
+
+	plan, err := buildCreatePlan(nil, opts, params, machineID, logger)
+	if err != nil {
+		return err
+	}
+	if renderComposeOnly {
+		fmt.Print(plan.ComposeFile)
+		return nil
+	}
+	plan.Write(os.Stdout)
+	return nil
+}
+
+// createPlan is what create would do for a machine, as printed by render and create --dry-run
+type createPlan struct {
+	MachineID   string
+	Options     [][2]string
+	SSHHost     string
+	SSHPort     int
+	PortSource  string
+	Calls       []string
+	ComposeFile string
+}
+
+// buildCreatePlan mirrors runCreate without side effects, taking its decisions from the same
+// helpers. With a client, the project, an existing workspace and the SSH port are looked up
+// read-only; without one nothing is contacted and the steps that depend on the server are
+// marked as conditional.
+func buildCreatePlan(dokployClient *dokploy.Client, opts *options.Options, params composeParams, machineID string, logger *logrus.Logger) (*createPlan, error) {
+	parsedURL, err := url.Parse(opts.DokployServerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server URL: %w", err)
+	}
+
+	plan := &createPlan{
+		MachineID: machineID,
+		Options:   resolvedCreateOptions(opts, params),
+		SSHHost:   strings.Split(parsedURL.Host, ":")[0],
+	}
+
+	// Server state create depends on; unknown without a client
+	projectKnown, projectExists := false, false
+	var existing *dokploy.Compose
+	var existingMarker *workspaceMarker
+	if dokployClient != nil {
+		projectID, err := findProjectID(dokployClient, opts.DokployProjectName)
+		if err != nil {
+			return nil, err
+		}
+		projectKnown, projectExists = true, projectID != ""
+		if projectExists {
+			if existing, existingMarker, err = findExistingWorkspace(dokployClient, projectID, machineID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	unless := func(known bool, condition string) string {
+		if known {
+			return ""
+		}
+		return " (only " + condition + ")"
+	}
+
+	plan.Calls = append(plan.Calls, "GET  /api/project.all - look up project "+opts.DokployProjectName)
+	if !projectExists {
+		plan.Calls = append(plan.Calls, "POST /api/project.create - create project "+opts.DokployProjectName+unless(projectKnown, "if it does not exist"))
+	}
+	plan.Calls = append(plan.Calls, "GET  /api/project.all - look for an existing workspace "+machineID)
+	if existing != nil {
+		plan.Calls = append(plan.Calls, "GET  /api/compose.one - load existing workspace "+existing.ComposeID)
+	}
+
+	if opts.Isolation == options.IsolationSysbox && needsIsolationCheck(opts, existingMarker) {
+		plan.Calls = append(plan.Calls,
+			"POST /api/compose.create - create the sysbox runtime check service",
+			"POST /api/compose.update - upload the sysbox runtime check",
+			"POST /api/compose.deploy - deploy the sysbox runtime check",
+			"GET  /api/compose.one - poll the sysbox runtime check",
+			"POST /api/compose.delete - remove the sysbox runtime check")
+	}
+	if opts.SharedCache {
+		plan.Calls = append(plan.Calls,
+			"GET  /api/project.all - look up the shared image cache "+cacheComposeName+" in every project",
+			"POST /api/compose.create, compose.update, compose.deploy - deploy the shared image cache (only if the node has none or it is stopped)")
+	}
+
+	// SSH port: reused from an existing workspace, probed on the server, or a stand-in
+	switch {
+	case reusableSSHPort(existingMarker) > 0:
+		plan.SSHPort = reusableSSHPort(existingMarker)
+		plan.PortSource = "reused from the existing workspace"
+	case dokployClient != nil:
+		plan.Calls = append(plan.Calls, "GET  /api/project.all - check SSH ports in use")
+		if plan.SSHPort, err = findAvailableSSHPort(dokployClient, plan.SSHHost, logger); err != nil {
+			return nil, err
+		}
+		plan.PortSource = fmt.Sprintf("first free port in %d-%d on %s", sshPortRangeStart, sshPortRangeEnd, plan.SSHHost)
+	default:
+		plan.SSHPort = renderSSHPort
+		plan.PortSource = fmt.Sprintf("--ssh-port; create picks the first free port in %d-%d", sshPortRangeStart, sshPortRangeEnd)
+	}
+
+	publicKey := renderPublicKey
+	if machineFolder := os.Getenv("MACHINE_FOLDER"); machineFolder != "" {
+		if publicKey, err = loadMachinePublicKey(machineFolder, logger); err != nil {
+			return nil, err
+		}
+	}
+
+	params.MachineID = machineID
+	params.SSHPort = plan.SSHPort
+	params.SSHPublicKey = publicKey
+	params.ComposeID = renderComposeID
+	if existing != nil {
+		params.ComposeID = existing.ComposeID
+	} else {
+		plan.Calls = append(plan.Calls, "POST /api/compose.create - create compose service "+machineID)
+	}
+	if plan.ComposeFile, err = generateDockerCompose(params, logger); err != nil {
+		return nil, fmt.Errorf("failed to generate Docker Compose configuration: %w", err)
+	}
+
+	composeChanged := composeNeedsUpdate(existing, plan.ComposeFile, inactivityComposeEnv(opts))
+	if composeChanged {
+		plan.Calls = append(plan.Calls, "POST /api/compose.update - upload the compose file")
+	}
+	if composeNeedsDeploy(existing, composeChanged) {
+		plan.Calls = append(plan.Calls, "POST /api/compose.deploy - deploy the workspace")
+	}
+	plan.Calls = append(plan.Calls,
+		"GET  /api/compose.one - poll the deployment",
+		"GET  /api/compose.one, docker.getContainersByAppNameMatch, docker.getConfig - wait for the workspace setup")
+
+	return plan, nil
+}
+
+// Write prints the plan: options, SSH endpoint, API calls and the compose file
+func (p *createPlan) Write(out io.Writer) {
+	fmt.Fprintf(out, "# Workspace %s\n\n", p.MachineID)
+
+	fmt.Fprintln(out, "# Resolved options")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, option := range p.Options {
+		fmt.Fprintf(w, "%s\t%s\n", option[0], orDash(option[1]))
+	}
+	w.Flush()
+
+	fmt.Fprintf(out, "\n# SSH\n%s:%d (%s)\n", p.SSHHost, p.SSHPort, p.PortSource)
+
+	fmt.Fprintln(out, "\n# Dokploy API calls")
+	for i, call := range p.Calls {
+		fmt.Fprintf(out, "%2d. %s\n", i+1, call)
+	}
+
+	fmt.Fprintf(out, "\n# Compose file\n%s", p.ComposeFile)
+}
+
+// resolvedCreateOptions lists the options create renders the workspace from, with secrets hidden
+func resolvedCreateOptions(opts *options.Options, params composeParams) [][2]string {
+	secret := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return "(set)"
+	}
+	var allowed []string
+	for _, service := range params.AllowedServices {
+		allowed = append(allowed, fmt.Sprintf("%s%v", service.Name, service.Ports))
+	}
+
+	return [][2]string{
+		{"DOKPLOY_SERVER_URL", opts.DokployServerURL},
+		{"DOKPLOY_API_TOKEN", secret(opts.DokployAPIToken)},
+		{"DOKPLOY_PROJECT_NAME", opts.DokployProjectName},
+		{"DOKPLOY_SERVER_ID", opts.DokployServerID},
+		{"MACHINE_TYPE", fmt.Sprintf("%s (cpus: %s, memory: %s, swap: %s, pids: %d, shm: %s)", params.MachineTypeName,
+			params.MachineType.CPUs, params.MachineType.Memory, params.MachineType.MemorySwap, params.MachineType.PidsLimit, params.MachineType.ShmSize)},
+		{"DOKPLOY_WORKSPACE_IMAGE", params.Image},
+		{"DOKPLOY_ISOLATION", params.Isolation},
+		{"DOKPLOY_SIDECARS", strings.Join(sidecarNames(params.Sidecars), ", ")},
+		{"DOKPLOY_POST_SETUP_SCRIPT", secret(params.PostSetupScript)},
+		{"DOKPLOY_DOTFILES_URL", params.DotfilesURL},
+		{"DOKPLOY_JOIN_DOKPLOY_NETWORK", fmt.Sprint(params.JoinDokployNetwork)},
+		{"DOKPLOY_ALLOWED_SERVICES", strings.Join(allowed, ", ")},
+		{"DOKPLOY_SHARED_CACHE", fmt.Sprint(opts.SharedCache)},
+		{"DOKPLOY_INACTIVITY_TIMEOUT", fmt.Sprint(params.InactivityTimeout)},
+		{"DOKPLOY_INACTIVITY_API_TOKEN", secret(opts.InactivityAPIToken)},
+		{"DOKPLOY_START_TIMEOUT", fmt.Sprint(opts.StartTimeout)},
+	}
+}
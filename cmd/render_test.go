@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+)
+
+// fakeDokploy is an in-memory Dokploy API with the endpoints create uses. It records the POST
+// calls it receives. Workspace containers report unhealthy, so create returns right after the
+// deployment instead of waiting for SSH.
+type fakeDokploy struct {
+	mu       sync.Mutex
+	projects []dokploy.Project
+	composes map[string]*dokploy.Compose
+	posts    []string
+
+	// created, when set, is called with every compose service created
+	created func(compose *dokploy.Compose)
+}
+
+func (f *fakeDokploy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var body map[string]interface{}
+	if r.Method == http.MethodPost {
+		f.posts = append(f.posts, r.URL.Path)
+		json.NewDecoder(r.Body).Decode(&body)
+	}
+	field := func(name string) string {
+		value, _ := body[name].(string)
+		return value
+	}
+
+	var response interface{}
+	switch r.URL.Path {
+	case "/api/project.all":
+		projects := make([]dokploy.Project, len(f.projects))
+		for i, project := range f.projects {
+			projects[i] = project
+			projects[i].Composes = nil
+			for _, compose := range f.composes {
+				if compose.ProjectID == project.ProjectID {
+					projects[i].Composes = append(projects[i].Composes, dokploy.Compose{
+						ComposeID: compose.ComposeID,
+						Name:      compose.Name,
+						Status:    compose.Status,
+						CreatedAt: compose.CreatedAt,
+					})
+				}
+			}
+		}
+		response = projects
+	case "/api/project.create":
+		project := dokploy.Project{ProjectID: fmt.Sprintf("project-%d", len(f.projects)+1), Name: field("name")}
+		f.projects = append(f.projects, project)
+		response = project
+	case "/api/compose.create":
+		compose := &dokploy.Compose{
+			ComposeID:   fmt.Sprintf("compose-%d", len(f.composes)+1),
+			Name:        field("name"),
+			Description: field("description"),
+			ProjectID:   field("projectId"),
+			AppName:     field("name") + "-app",
+			Status:      "idle",
+			CreatedAt:   fmt.Sprintf("2026-01-01T00:%02d:00Z", len(f.composes)),
+		}
+		f.composes[compose.ComposeID] = compose
+		if f.created != nil {
+			f.created(compose)
+		}
+		response = compose
+	case "/api/compose.update":
+		compose := f.composes[field("composeId")]
+		compose.ComposeFile = field("composeFile")
+		compose.Env = field("env")
+		response = true
+	case "/api/compose.deploy":
+		compose := f.composes[field("composeId")]
+		compose.Status = "done"
+		compose.Deployments = append(compose.Deployments, dokploy.Deployment{
+			DeploymentID: fmt.Sprintf("deployment-%d", len(compose.Deployments)+1),
+			Status:       "done",
+			CreatedAt:    fmt.Sprintf("2026-01-01T00:00:%02dZ", len(compose.Deployments)),
+		})
+		response = true
+	case "/api/compose.delete":
+		delete(f.composes, field("composeId"))
+		response = true
+	case "/api/compose.one":
+		compose, ok := f.composes[r.URL.Query().Get("composeId")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			response = map[string]string{"code": "NOT_FOUND"}
+			break
+		}
+		response = compose
+	case "/api/docker.getContainersByAppNameMatch":
+		response = []dokploy.Container{{ContainerID: "container-1", Name: r.URL.Query().Get("appName") + "-devpod-workspace-1", State: "running"}}
+	case "/api/docker.getConfig":
+		response = map[string]interface{}{"State": map[string]interface{}{
+			"Status": "running",
+			"Health": map[string]interface{}{"Status": "unhealthy", "Log": []map[string]interface{}{{"ExitCode": 1, "Output": "test"}}},
+		}}
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// takePosts returns the POST calls recorded since the last call
+func (f *fakeDokploy) takePosts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	posts := f.posts
+	f.posts = nil
+	return posts
+}
+
+// planPosts returns the POST endpoints of a create plan
+func planPosts(plan *createPlan) []string {
+	var posts []string
+	for _, call := range plan.Calls {
+		if fields := strings.Fields(call); len(fields) > 1 && fields[0] == "POST" {
+			posts = append(posts, fields[1])
+		}
+	}
+	return posts
+}
+
+func TestCreatePlanMatchesCreate(t *testing.T) {
+	fake := &fakeDokploy{composes: map[string]*dokploy.Compose{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	t.Setenv("DOKPLOY_SERVER_URL", server.URL)
+	t.Setenv("DOKPLOY_API_TOKEN", "token")
+	t.Setenv("DEVPOD_MACHINE_ID", "ws")
+	t.Setenv("MACHINE_FOLDER", t.TempDir())
+
+	steps := []struct {
+		name   string
+		before func()
+		want   []string
+	}{
+		{
+			name: "new project",
+			want: []string{"/api/project.create", "/api/compose.create", "/api/compose.update", "/api/compose.deploy"},
+		},
+		{name: "workspace is current"},
+		{
+			name:   "workspace is stopped",
+			before: func() { fake.composes["compose-1"].Status = "idle" },
+			want:   []string{"/api/compose.deploy"},
+		},
+		{
+			name:   "compose file changed",
+			before: func() { fake.composes["compose-1"].ComposeFile += "\n# edited\n" },
+			want:   []string{"/api/compose.update", "/api/compose.deploy"},
+		},
+		{
+			name:   "workspace deleted",
+			before: func() { delete(fake.composes, "compose-1") },
+			want:   []string{"/api/compose.create", "/api/compose.update", "/api/compose.deploy"},
+		},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.before != nil {
+				step.before()
+			}
+
+			opts, err := options.LoadFromEnv()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := buildComposeParams(opts, testLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := dokploy.NewClient(opts, testLogger())
+			plan, err := buildCreatePlan(client, opts, params, "ws", testLogger())
+			if err != nil {
+				t.Fatalf("buildCreatePlan() error = %v", err)
+			}
+			if posts := fake.takePosts(); len(posts) != 0 {
+				t.Fatalf("buildCreatePlan() made POST calls %v", posts)
+			}
+
+			// The fake workspace is unhealthy, which ends create after the deployment
+			if err := runCreate(); err == nil || !strings.Contains(err.Error(), "unhealthy") {
+				t.Fatalf("runCreate() error = %v, want the unhealthy workspace error", err)
+			}
+
+			planned, made := planPosts(plan), fake.takePosts()
+			if !reflect.DeepEqual(planned, made) {
+				t.Errorf("plan lists POST calls %v, create made %v", planned, made)
+			}
+			if !reflect.DeepEqual(made, step.want) {
+				t.Errorf("create made POST calls %v, want %v", made, step.want)
+			}
+		})
+	}
+}
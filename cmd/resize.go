@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	resizeMachineType string
+	resizeTimeout     time.Duration
+)
+
+// resizeCmd represents the resize command
+var resizeCmd = &cobra.Command{
+	Use:   "resize",
+	Short: "Change the machine type of a Dokploy workspace",
+	Long: `Change the machine type of the workspace of the current machine (MACHINE_ID) in place.
+
+The compose file is rendered again with the new resource limits and redeployed. The SSH port,
+SSH host keys, named volumes, isolation mode, image, inactivity token and the settings recorded
+in the x-devpod block (sidecars, networks, daemon options, hooks, inactivity) stay the same,
+whatever the current options are, and MACHINE_TYPE of the DevPod machine is updated to the new
+type. The resize is refused when recreating the containers would lose data, e.g. because a
+volume is anonymous, and for workspaces created before settings were recorded when the current
+options render other settings.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runResize()
+	},
+}
+
+func init() {
+	resizeCmd.Flags().StringVar(&resizeMachineType, "machine-type", "", "the new machine type (e.g. large)")
+	resizeCmd.Flags().DurationVar(&resizeTimeout, "timeout", 0, "how long to wait for the workspace to accept SSH connections (default: DOKPLOY_START_TIMEOUT)")
+	resizeCmd.MarkFlagRequired("machine-type")
+	rootCmd.AddCommand(resizeCmd)
+}
+
+func runResize() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	machineID, err := getMachineIDFromContext()
+	if err != nil {
+		return fmt.Errorf("failed to get machine ID: %w", err)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	// Validate the new type before touching the workspace
+	requested := *opts
+	requested.MachineType = resizeMachineType
+	machineType, err := requested.ResolveMachineType()
+	if err != nil {
+		return fmt.Errorf("invalid machine type: %w", err)
+	}
+
+	timeout := opts.StartTimeout
+	if resizeTimeout > 0 {
+		timeout = resizeTimeout
+	}
+
+	client := dokploy.NewClient(opts, logger)
+
+	projectID, err := findProjectID(client, opts.DokployProjectName)
+	if err != nil {
+		return err
+	}
+	if projectID == "" {
+		return fmt.Errorf("project '%s' not found", opts.DokployProjectName)
+	}
+	compose, marker, err := findExistingWorkspace(client, projectID, machineID)
+	if err != nil {
+		return err
+	}
+	if compose == nil {
+		return fmt.Errorf("workspace %s not found in project '%s'", machineID, opts.DokployProjectName)
+	}
+	if marker != nil && marker.Adopted {
+		return fmt.Errorf("workspace %s was adopted; change its resources in its own compose file", machineID)
+	}
+	if marker != nil && marker.MachineType == resizeMachineType {
+		logger.Infof("✓ Workspace %s already uses machine type '%s'", machineID, resizeMachineType)
+		return nil
+	}
+
+	machineFolder, err := findMachineFolder(machineID)
+	if err != nil {
+		return err
+	}
+	publicKey, err := loadMachinePublicKey(machineFolder, logger)
+	if err != nil {
+		return err
+	}
+	dockerComposeContent, env, err := resizedCompose(opts, compose, resizeMachineType, publicKey, logger)
+	if err != nil {
+		return err
+	}
+
+	logger.Infof("Resizing workspace %s to '%s' (cpus: %s, memory: %s)...",
+		machineID, resizeMachineType, machineType.CPUs, machineType.Memory)
+	if err := client.SaveComposeFile(dokploy.SaveComposeFileRequest{
+		ComposeID:     compose.ComposeID,
+		DockerCompose: dockerComposeContent,
+		Env:           env,
+	}); err != nil {
+		return fmt.Errorf("failed to save Docker Compose file: %w", err)
+	}
+
+	// Record the new type in the DevPod machine, so that the next create does not reconcile it back
+	if err := saveMachineOptions(machineFolder, map[string]string{"MACHINE_TYPE": resizeMachineType}); err != nil {
+		return err
+	}
+
+	if err := redeployCompose(client, compose, timeout, logger); err != nil {
+		return err
+	}
+	if err := waitForWorkspaceReady(client, compose.ComposeID, opts, timeout, logger); err != nil {
+		return err
+	}
+
+	logger.Infof("✓ Workspace %s resized to '%s'", machineID, resizeMachineType)
+	return nil
+}
+
+// resizedCompose renders the compose file and environment of a workspace (with its compose file
+// loaded) with another machine type. Everything else stays the way the workspace was created.
+func resizedCompose(opts *options.Options, compose *dokploy.Compose, machineType, publicKey string, logger *logrus.Logger) (string, string, error) {
+	machineID := compose.Name
+	marker, err := parseWorkspaceMarker(compose.ComposeFile)
+	if err != nil {
+		return "", "", err
+	}
+	workspaceOpts, recorded, err := workspaceOptions(opts, compose)
+	if err != nil {
+		return "", "", err
+	}
+	workspaceOpts.MachineType = machineType
+	params, err := buildComposeParams(workspaceOpts, logger)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Keep the SSH port DevPod knows the machine by
+	sshPort, err := extractSSHPortFromCompose(compose, workspaceOpts, logger)
+	if err != nil || sshPort == 0 {
+		return "", "", fmt.Errorf("SSH port of workspace %s not found: %v", machineID, err)
+	}
+
+	params.MachineID = machineID
+	if marker != nil {
+		params.SSHHost = marker.SSHHost
+	}
+	params.SSHPort = sshPort
+	params.SSHPublicKey = publicKey
+	params.ComposeID = compose.ComposeID
+	dockerComposeContent, err := generateDockerCompose(params, logger)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate Docker Compose configuration: %w", err)
+	}
+
+	if err := checkVolumesPreserved(compose.ComposeFile, dockerComposeContent); err != nil {
+		return "", "", fmt.Errorf("refusing to resize workspace %s: %w", machineID, err)
+	}
+	if !recorded {
+		if err := checkSettingsPreserved(compose.ComposeFile, dockerComposeContent); err != nil {
+			return "", "", fmt.Errorf("refusing to resize workspace %s: %w", machineID, err)
+		}
+	}
+	return dockerComposeContent, inactivityComposeEnv(workspaceOpts), nil
+}
+
+// redeployCompose deploys a compose service and waits for the new deployment to finish
+func redeployCompose(client *dokploy.Client, compose *dokploy.Compose, timeout time.Duration, logger *logrus.Logger) error {
+	previous := ""
+	if deployment := compose.LatestDeployment(); deployment != nil {
+		previous = deployment.DeploymentID
+	}
+
+	if err := client.DeployCompose(dokploy.DeployComposeRequest{ComposeID: compose.ComposeID}); err != nil {
+		return fmt.Errorf("failed to deploy Docker Compose service: %w", err)
+	}
+	logger.Info("✓ Docker Compose deployment started")
+
+	return waitForDeployment(client, compose.ComposeID, previous, timeout, logger)
+}
+
+// checkVolumesPreserved compares the mounts of the current and the new compose file. Every
+// mount of the current file must stay backed by the same volume or bind path; anonymous
+// volumes are refused since recreating the container loses them.
+func checkVolumesPreserved(currentFile, newFile string) error {
+	current, err := composeMounts(currentFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the current compose file: %w", err)
+	}
+	next, err := composeMounts(newFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the new compose file: %w", err)
+	}
+
+	var problems []string
+	for service, mounts := range current {
+		for target, source := range mounts {
+			switch {
+			case source == "tmpfs":
+				continue
+			case source == "anonymous":
+				problems = append(problems, fmt.Sprintf("%s keeps %s in an anonymous volume", service, target))
+			case next[service] == nil:
+				problems = append(problems, fmt.Sprintf("service %s with %s (%s) would be removed", service, target, source))
+			case next[service][target] != source:
+				problems = append(problems, fmt.Sprintf("%s would mount %s from %s instead of %s", service, target, orDash(next[service][target]), source))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("recreating the containers would lose data: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// composeMounts returns the mounts of every service of a compose file as target → source,
+// where the source is "volume:<name>", "bind:<path>", "anonymous" or "tmpfs"
+func composeMounts(composeFile string) (map[string]map[string]string, error) {
+	var document struct {
+		Services map[string]struct {
+			Volumes []interface{} `yaml:"volumes"`
+		} `yaml:"services"`
+		Volumes map[string]*struct {
+			Name string `yaml:"name"`
+		} `yaml:"volumes"`
+	}
+	if err := yaml.Unmarshal([]byte(composeFile), &document); err != nil {
+		return nil, err
+	}
+
+	// Volumes without an explicit name are scoped to the compose project, which a redeploy keeps
+	volumeSource := func(key string) string {
+		if volume := document.Volumes[key]; volume != nil && volume.Name != "" {
+			return "volume:" + volume.Name
+		}
+		return "volume:" + key
+	}
+
+	mounts := map[string]map[string]string{}
+	for name, service := range document.Services {
+		mounts[name] = map[string]string{}
+		for _, entry := range service.Volumes {
+			var source, target, mountType string
+			switch volume := entry.(type) {
+			case string:
+				parts := strings.Split(volume, ":")
+				if len(parts) == 1 {
+					target = parts[0]
+				} else {
+					source, target = parts[0], parts[1]
+				}
+			case map[string]interface{}:
+				source, _ = volume["source"].(string)
+				target, _ = volume["target"].(string)
+				mountType, _ = volume["type"].(string)
+			default:
+				return nil, fmt.Errorf("unsupported volume entry in service %s", name)
+			}
+
+			switch {
+			case mountType == "tmpfs":
+				mounts[name][target] = "tmpfs"
+			case source == "":
+				mounts[name][target] = "anonymous"
+			case mountType == "bind" || strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~"):
+				mounts[name][target] = "bind:" + source
+			default:
+				mounts[name][target] = volumeSource(source)
+			}
+		}
+	}
+	return mounts, nil
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+)
+
+func TestComposeMounts(t *testing.T) {
+	tests := []struct {
+		name        string
+		composeFile string
+		want        map[string]map[string]string
+		wantErr     bool
+	}{
+		{
+			name: "short syntax",
+			composeFile: `services:
+  workspace:
+    volumes:
+      - workspace-data:/workspace
+      - ./config:/etc/app:ro
+      - /var/cache
+volumes:
+  workspace-data:
+`,
+			want: map[string]map[string]string{"workspace": {
+				"/workspace": "volume:workspace-data",
+				"/etc/app":   "bind:./config",
+				"/var/cache": "anonymous",
+			}},
+		},
+		{
+			name: "long syntax",
+			composeFile: `services:
+  db:
+    volumes:
+      - type: volume
+        source: db-data
+        target: /var/lib/postgresql/data
+      - type: bind
+        source: /srv/backups
+        target: /backups
+      - type: tmpfs
+        target: /tmp
+volumes:
+  db-data:
+    name: shared-db-data
+`,
+			want: map[string]map[string]string{"db": {
+				"/var/lib/postgresql/data": "volume:shared-db-data",
+				"/backups":                 "bind:/srv/backups",
+				"/tmp":                     "tmpfs",
+			}},
+		},
+		{name: "no volumes", composeFile: "services:\n  app:\n    image: nginx\n", want: map[string]map[string]string{"app": {}}},
+		{name: "unsupported entry", composeFile: "services:\n  app:\n    volumes:\n      - [a, b]\n", wantErr: true},
+		{name: "invalid YAML", composeFile: "services: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := composeMounts(tt.composeFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("composeMounts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("composeMounts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckVolumesPreserved(t *testing.T) {
+	const current = `services:
+  workspace:
+    volumes:
+      - workspace-data:/workspace
+      - type: tmpfs
+        target: /tmp
+  db:
+    volumes:
+      - db-data:/var/lib/postgresql/data
+volumes:
+  workspace-data:
+  db-data:
+`
+
+	tests := []struct {
+		name    string
+		current string
+		next    string
+		wantErr bool
+	}{
+		{name: "same mounts", current: current, next: current},
+		{
+			name:    "tmpfs may change",
+			current: current,
+			next:    "services:\n  workspace:\n    volumes:\n      - workspace-data:/workspace\n  db:\n    volumes:\n      - db-data:/var/lib/postgresql/data\n",
+		},
+		{
+			name:    "new mounts are allowed",
+			current: "services:\n  workspace:\n    volumes:\n      - workspace-data:/workspace\n",
+			next:    "services:\n  workspace:\n    volumes:\n      - workspace-data:/workspace\n      - cache:/root/.cache\n",
+		},
+		{
+			name:    "service removed",
+			current: current,
+			next:    "services:\n  workspace:\n    volumes:\n      - workspace-data:/workspace\n",
+			wantErr: true,
+		},
+		{
+			name:    "volume replaced",
+			current: current,
+			next:    "services:\n  workspace:\n    volumes:\n      - other-data:/workspace\n  db:\n    volumes:\n      - db-data:/var/lib/postgresql/data\n",
+			wantErr: true,
+		},
+		{
+			name:    "anonymous volume",
+			current: "services:\n  db:\n    volumes:\n      - /var/lib/postgresql/data\n",
+			next:    "services:\n  db:\n    volumes:\n      - /var/lib/postgresql/data\n",
+			wantErr: true,
+		},
+		{name: "invalid current file", current: "services: [", next: current, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkVolumesPreserved(tt.current, tt.next); (err != nil) != tt.wantErr {
+				t.Errorf("checkVolumesPreserved() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResizedCompose(t *testing.T) {
+	created := testOptions()
+	created.Sidecars = "services:\n  redis:\n    image: redis:7\n"
+	created.InactivityTimeout = time.Hour
+	created.InactivityAPIToken = "workspace-token"
+	recorded := renderTestCompose(t, created)
+	legacy := regexp.MustCompile(`(?m)^  settings:\n(    .*\n)*`).ReplaceAllString(recorded, "")
+
+	// The caller has none of the settings the workspace was created with
+	caller := testOptions()
+	caller.InactivityAPIToken = "caller-token"
+
+	tests := []struct {
+		name    string
+		compose dokploy.Compose
+		wantErr string
+	}{
+		{name: "recorded settings", compose: dokploy.Compose{Name: "ws", ComposeID: "compose-1", ComposeFile: recorded, Env: "DEVPOD_INACTIVITY_TOKEN=workspace-token"}},
+		{
+			name:    "legacy workspace with other settings",
+			compose: dokploy.Compose{Name: "ws", ComposeID: "compose-1", ComposeFile: legacy, Env: "DEVPOD_INACTIVITY_TOKEN=workspace-token"},
+			wantErr: "service redis would be removed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, env, err := resizedCompose(caller, &tt.compose, "large", `ssh-ed25519 AAAAC3Nza "devpod"`, testLogger())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resizedCompose() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resizedCompose() error = %v", err)
+			}
+
+			// Only the machine type changes
+			resized := *created
+			resized.MachineType = "large"
+			if want := renderTestCompose(t, &resized); got != want {
+				t.Errorf("resizedCompose() =\n%s\nwant\n%s", got, want)
+			}
+			if want := inactivityComposeEnv(&resized); env != want {
+				t.Errorf("resizedCompose() env = %q, want %q", env, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"gopkg.in/yaml.v3"
+)
+
+// settingsEnvironment are the environment variables of the workspace service rendered from the
+// workspace settings
+var settingsEnvironment = []string{"DOCKER_DAEMON_CONFIG", "DEVPOD_POST_SETUP_SCRIPT", "DEVPOD_DOTFILES_URL", "DEVPOD_INACTIVITY_TIMEOUT"}
+
+// workspaceSettings are the options a workspace is rendered from besides its machine type,
+// isolation and image. They are recorded in the marker, so that upgrade, resize and migrate
+// render a workspace with its own settings instead of those of the caller. The sidecar
+// definition and the post-setup script are base64-encoded, so that compose does not
+// interpolate them.
+type workspaceSettings struct {
+	Sidecars                 string        `yaml:"sidecars,omitempty"`
+	JoinDokployNetwork       bool          `yaml:"join-dokploy-network,omitempty"`
+	AllowedServices          []string      `yaml:"allowed-services,omitempty"`
+	SharedCache              bool          `yaml:"shared-cache,omitempty"`
+	DockerRegistryMirrors    []string      `yaml:"docker-registry-mirrors,omitempty"`
+	DockerInsecureRegistries []string      `yaml:"docker-insecure-registries,omitempty"`
+	DockerAddressPool        string        `yaml:"docker-address-pool,omitempty"`
+	DockerMTU                int           `yaml:"docker-mtu,omitempty"`
+	DockerLogMaxSize         string        `yaml:"docker-log-max-size,omitempty"`
+	DockerLogMaxFile         int           `yaml:"docker-log-max-file,omitempty"`
+	DockerStorageDriver      string        `yaml:"docker-storage-driver,omitempty"`
+	PostSetupScript          string        `yaml:"post-setup-script,omitempty"`
+	DotfilesURL              string        `yaml:"dotfiles-url,omitempty"`
+	InactivityTimeout        time.Duration `yaml:"inactivity-timeout,omitempty"`
+}
+
+// settingsFromOptions returns the workspace settings of the options, with the sidecar
+// definition and post-setup script read from their files
+func settingsFromOptions(opts *options.Options) (*workspaceSettings, error) {
+	sidecars, err := opts.SidecarDefinition()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sidecar services: %w", err)
+	}
+	postSetupScript, err := opts.PostSetupScript()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load post-setup script: %w", err)
+	}
+
+	return &workspaceSettings{
+		Sidecars:                 encodeSetting(sidecars),
+		JoinDokployNetwork:       opts.JoinDokployNetwork,
+		AllowedServices:          opts.AllowedServices,
+		SharedCache:              opts.SharedCache,
+		DockerRegistryMirrors:    opts.DockerRegistryMirrors,
+		DockerInsecureRegistries: opts.DockerInsecureRegistries,
+		DockerAddressPool:        opts.DockerAddressPool,
+		DockerMTU:                opts.DockerMTU,
+		DockerLogMaxSize:         opts.DockerLogMaxSize,
+		DockerLogMaxFile:         opts.DockerLogMaxFile,
+		DockerStorageDriver:      opts.DockerStorageDriver,
+		PostSetupScript:          encodeSetting(postSetupScript),
+		DotfilesURL:              opts.DotfilesURL,
+		InactivityTimeout:        opts.InactivityTimeout,
+	}, nil
+}
+
+// apply sets the recorded settings on the options
+func (s *workspaceSettings) apply(opts *options.Options) error {
+	sidecars, err := base64.StdEncoding.DecodeString(s.Sidecars)
+	if err != nil {
+		return fmt.Errorf("invalid sidecars in the workspace settings: %w", err)
+	}
+	postSetupScript, err := base64.StdEncoding.DecodeString(s.PostSetupScript)
+	if err != nil {
+		return fmt.Errorf("invalid post-setup script in the workspace settings: %w", err)
+	}
+
+	opts.Sidecars = string(sidecars)
+	opts.JoinDokployNetwork = s.JoinDokployNetwork
+	opts.AllowedServices = s.AllowedServices
+	opts.SharedCache = s.SharedCache
+	opts.DockerRegistryMirrors = s.DockerRegistryMirrors
+	opts.DockerInsecureRegistries = s.DockerInsecureRegistries
+	opts.DockerAddressPool = s.DockerAddressPool
+	opts.DockerMTU = s.DockerMTU
+	opts.DockerLogMaxSize = s.DockerLogMaxSize
+	opts.DockerLogMaxFile = s.DockerLogMaxFile
+	opts.DockerStorageDriver = s.DockerStorageDriver
+	opts.PostSetupScriptValue = string(postSetupScript)
+	opts.DotfilesURL = s.DotfilesURL
+	opts.InactivityTimeout = s.InactivityTimeout
+	return nil
+}
+
+// differences returns the names of the settings that differ from other
+func (s *workspaceSettings) differences(other *workspaceSettings) []string {
+	var names []string
+	a, b := reflect.ValueOf(*s), reflect.ValueOf(*other)
+	for i := 0; i < a.NumField(); i++ {
+		// Printed, an empty list and a missing one are the same
+		if fmt.Sprint(a.Field(i).Interface()) != fmt.Sprint(b.Field(i).Interface()) {
+			name, _, _ := strings.Cut(a.Type().Field(i).Tag.Get("yaml"), ",")
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// settingsLines renders the settings block of the marker
+func settingsLines(settings *workspaceSettings) ([]string, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	var buffer bytes.Buffer
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(map[string]*workspaceSettings{"settings": settings}); err != nil {
+		return nil, fmt.Errorf("failed to encode workspace settings: %w", err)
+	}
+	return strings.Split(strings.TrimRight(buffer.String(), "\n"), "\n"), nil
+}
+
+func encodeSetting(value string) string {
+	if value == "" {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(value))
+}
+
+// workspaceOptions returns the options a provider-owned workspace is rendered with again: its
+// recorded marker settings win over opts, and the inactivity token is kept from its compose
+// environment. recorded is false for workspaces that predate recorded settings; their compose
+// file rendered from opts has to pass checkSettingsPreserved.
+func workspaceOptions(opts *options.Options, compose *dokploy.Compose) (workspaceOpts *options.Options, recorded bool, err error) {
+	marker, err := parseWorkspaceMarker(compose.ComposeFile)
+	if err != nil {
+		return nil, false, err
+	}
+	if workspaceOpts, err = markerOptions(opts, marker); err != nil {
+		return nil, false, err
+	}
+	if token := composeEnvValue(compose.Env, "DEVPOD_INACTIVITY_TOKEN"); token != "" {
+		workspaceOpts.InactivityAPIToken = token
+	}
+	return workspaceOpts, marker != nil && marker.Settings != nil, nil
+}
+
+// composeEnvValue returns the value of a KEY=value line of a compose environment
+func composeEnvValue(env, key string) string {
+	for _, line := range strings.Split(env, "\n") {
+		if value, found := strings.CutPrefix(strings.TrimSpace(line), key+"="); found {
+			return value
+		}
+	}
+	return ""
+}
+
+// checkSettingsPreserved compares the parts of two workspace compose files that are rendered
+// from the workspace settings: the services besides the workspace, the networks, and the
+// daemon, hook and inactivity environment of the workspace. Workspaces that predate recorded
+// settings are only known by their compose file, so a new file rendered from the current options
+// must not change these parts.
+func checkSettingsPreserved(currentFile, newFile string) error {
+	current, err := renderedSettings(currentFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the current compose file: %w", err)
+	}
+	next, err := renderedSettings(newFile)
+	if err != nil {
+		return fmt.Errorf("failed to read the new compose file: %w", err)
+	}
+
+	keys := map[string]bool{}
+	for key := range current {
+		keys[key] = true
+	}
+	for key := range next {
+		keys[key] = true
+	}
+	var sorted []string
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var differences []string
+	for _, key := range sorted {
+		before, after := current[key], next[key]
+		switch {
+		case before == after:
+		case before == "":
+			differences = append(differences, key+" would be added")
+		case after == "":
+			differences = append(differences, key+" would be removed")
+		default:
+			differences = append(differences, key+" would change")
+		}
+	}
+	if len(differences) > 0 {
+		return fmt.Errorf("the workspace predates recorded settings and the current options differ from the ones it was created with: %s; "+
+			"set the options it was created with and try again", strings.Join(differences, "; "))
+	}
+	return nil
+}
+
+// renderedSettings returns the settings-dependent parts of a workspace compose file by name
+func renderedSettings(composeFile string) (map[string]string, error) {
+	var document struct {
+		Services map[string]map[string]interface{} `yaml:"services"`
+		Networks map[string]interface{}            `yaml:"networks"`
+	}
+	if err := yaml.Unmarshal([]byte(composeFile), &document); err != nil {
+		return nil, err
+	}
+
+	parts := map[string]string{}
+	add := func(key string, value interface{}) error {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		parts[key] = string(data)
+		return nil
+	}
+	for name, service := range document.Services {
+		if name != workspaceServiceName {
+			if err := add("service "+name, service); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// The private network belongs to the template
+		networks, _ := service["networks"].([]interface{})
+		for _, network := range networks {
+			if network != "workspace" {
+				parts[fmt.Sprintf("%s network %v", workspaceServiceName, network)] = "joined"
+			}
+		}
+		environment, _ := service["environment"].([]interface{})
+		for _, entry := range environment {
+			key, value, _ := strings.Cut(fmt.Sprint(entry), "=")
+			for _, name := range settingsEnvironment {
+				if key == name && value != "" && value != "0" {
+					parts[fmt.Sprintf("%s environment %s", workspaceServiceName, name)] = value
+				}
+			}
+		}
+	}
+	for name, network := range document.Networks {
+		if name != "workspace" {
+			if err := add("network "+name, network); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+)
+
+func TestWorkspaceOptions(t *testing.T) {
+	recordedOpts := testOptions()
+	recordedOpts.Sidecars = "services:\n  redis:\n    image: redis:7\n"
+	recordedOpts.PostSetupScriptValue = "echo $HOME"
+	recordedOpts.AllowedServices = []string{"postgres:5432"}
+	recordedOpts.InactivityTimeout = 30 * time.Minute
+	recordedOpts.InactivityAPIToken = "workspace-token"
+	recorded := renderTestCompose(t, recordedOpts)
+
+	caller := testOptions()
+	caller.JoinDokployNetwork = true
+	caller.DotfilesURL = "https://github.com/example/dotfiles"
+	caller.InactivityAPIToken = "caller-token"
+
+	tests := []struct {
+		name         string
+		compose      dokploy.Compose
+		wantRecorded bool
+		wantSidecars string
+		wantJoin     bool
+		wantToken    string
+	}{
+		{
+			name:         "recorded settings win",
+			compose:      dokploy.Compose{ComposeFile: recorded, Env: "DEVPOD_INACTIVITY_TOKEN=workspace-token"},
+			wantRecorded: true,
+			wantSidecars: strings.TrimSpace(recordedOpts.Sidecars),
+			wantToken:    "workspace-token",
+		},
+		{
+			name:      "no recorded settings",
+			compose:   dokploy.Compose{ComposeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n"},
+			wantJoin:  true,
+			wantToken: "caller-token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotRecorded, err := workspaceOptions(caller, &tt.compose)
+			if err != nil {
+				t.Fatalf("workspaceOptions() error = %v", err)
+			}
+			if gotRecorded != tt.wantRecorded {
+				t.Errorf("workspaceOptions() recorded = %v, want %v", gotRecorded, tt.wantRecorded)
+			}
+			if got.Sidecars != tt.wantSidecars || got.JoinDokployNetwork != tt.wantJoin || got.InactivityAPIToken != tt.wantToken {
+				t.Errorf("workspaceOptions() = sidecars %q, join %v, token %q, want %q, %v, %q",
+					got.Sidecars, got.JoinDokployNetwork, got.InactivityAPIToken, tt.wantSidecars, tt.wantJoin, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestSettingsDifferences(t *testing.T) {
+	base := testOptions()
+	other := testOptions()
+	other.Sidecars = "services:\n  redis:\n    image: redis:7\n"
+	other.DockerMTU = 1400
+	other.AllowedServices = []string{}
+
+	a, err := settingsFromOptions(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := settingsFromOptions(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := a.differences(b), []string{"sidecars", "docker-mtu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("differences() = %v, want %v", got, want)
+	}
+	if got := a.differences(a); len(got) != 0 {
+		t.Errorf("differences() with itself = %v", got)
+	}
+}
+
+func TestCheckSettingsPreserved(t *testing.T) {
+	render := func(modify func(*options.Options)) string {
+		opts := testOptions()
+		modify(opts)
+		return renderTestCompose(t, opts)
+	}
+	plain := render(func(*options.Options) {})
+
+	tests := []struct {
+		name    string
+		current string
+		next    string
+		wantErr string
+	}{
+		{name: "same settings", current: plain, next: render(func(o *options.Options) { o.MachineType = "large" })},
+		{
+			name:    "sidecar removed",
+			current: render(func(o *options.Options) { o.Sidecars = "services:\n  redis:\n    image: redis:7\n" }),
+			next:    plain,
+			wantErr: "service redis would be removed",
+		},
+		{
+			name:    "dokploy-network joined",
+			current: plain,
+			next:    render(func(o *options.Options) { o.JoinDokployNetwork = true }),
+			wantErr: "devpod-workspace network dokploy-network would be added",
+		},
+		{
+			name:    "hook changed",
+			current: render(func(o *options.Options) { o.PostSetupScriptValue = "make setup" }),
+			next:    render(func(o *options.Options) { o.PostSetupScriptValue = "make install" }),
+			wantErr: "DEVPOD_POST_SETUP_SCRIPT would change",
+		},
+		{
+			name:    "inactivity turned off",
+			current: render(func(o *options.Options) { o.InactivityTimeout = time.Hour; o.InactivityAPIToken = "token" }),
+			next:    plain,
+			wantErr: "DEVPOD_INACTIVITY_TIMEOUT would be removed",
+		},
+		{name: "baseline template joined dokploy-network", current: baselineCompose, next: plain, wantErr: "dokploy-network would be removed"},
+		{name: "invalid file", current: "services: [", next: plain, wantErr: "failed to read"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSettingsPreserved(tt.current, tt.next)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("checkSettingsPreserved() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("checkSettingsPreserved() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
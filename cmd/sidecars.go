@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// workspaceNetwork is the compose network key of the private per-workspace network
+const workspaceNetwork = "workspace"
+
+// workspaceServiceName is the compose service name of the workspace container
+const workspaceServiceName = "devpod-workspace"
+
+// sidecarSpec holds the user-declared services that run next to the workspace
+type sidecarSpec struct {
+	Services map[string]map[string]interface{} `yaml:"services"`
+	Volumes  map[string]interface{}            `yaml:"volumes"`
+}
+
+// parseSidecars parses a sidecar definition. It accepts either a compose-style document
+// with top-level services (and optional volumes) keys, or a bare map of services.
+func parseSidecars(definition string) (*sidecarSpec, error) {
+	if strings.TrimSpace(definition) == "" {
+		return nil, nil
+	}
+
+	var document map[string]interface{}
+	if err := yaml.Unmarshal([]byte(definition), &document); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar definition: %w", err)
+	}
+
+	spec := &sidecarSpec{}
+	if _, ok := document["services"]; ok {
+		if err := yaml.Unmarshal([]byte(definition), spec); err != nil {
+			return nil, fmt.Errorf("failed to parse sidecar services: %w", err)
+		}
+	} else if err := yaml.Unmarshal([]byte(definition), &spec.Services); err != nil {
+		return nil, fmt.Errorf("failed to parse sidecar services: %w", err)
+	}
+
+	for name, service := range spec.Services {
+		if name == workspaceServiceName {
+			return nil, fmt.Errorf("sidecar service name '%s' is reserved for the workspace", name)
+		}
+		if service == nil {
+			return nil, fmt.Errorf("sidecar service '%s' is empty", name)
+		}
+		if _, ok := service["image"]; !ok {
+			if _, ok := service["build"]; !ok {
+				return nil, fmt.Errorf("sidecar service '%s' must set an image", name)
+			}
+		}
+	}
+
+	return spec, nil
+}
+
+// extraServiceLines renders the sidecar and proxy services as YAML lines to be placed under services.
+// Every sidecar joins the private workspace network so the workspace reaches it by service name.
+func extraServiceLines(spec *sidecarSpec, proxies map[string]map[string]interface{}) ([]string, error) {
+	services := make(map[string]map[string]interface{})
+	if spec != nil {
+		for name, service := range spec.Services {
+			rendered := make(map[string]interface{}, len(service)+2)
+			for key, value := range service {
+				rendered[key] = value
+			}
+			rendered["networks"] = []string{workspaceNetwork}
+			if _, ok := rendered["restart"]; !ok {
+				rendered["restart"] = "unless-stopped"
+			}
+			services[name] = rendered
+		}
+	}
+
+	for name, proxy := range proxies {
+		if _, exists := services[name]; exists {
+			return nil, fmt.Errorf("sidecar service '%s' conflicts with the proxy for an allowed service", name)
+		}
+		services[name] = proxy
+	}
+
+	if len(services) == 0 {
+		return nil, nil
+	}
+	return marshalYAMLLines(services)
+}
+
+// sidecarVolumeLines renders the sidecar volumes as YAML lines to be placed under volumes
+func sidecarVolumeLines(spec *sidecarSpec) ([]string, error) {
+	if spec == nil || len(spec.Volumes) == 0 {
+		return nil, nil
+	}
+	return marshalYAMLLines(spec.Volumes)
+}
+
+// sidecarNames returns the sorted names of the sidecar services
+func sidecarNames(spec *sidecarSpec) []string {
+	if spec == nil {
+		return nil
+	}
+	names := make([]string, 0, len(spec.Services))
+	for name := range spec.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// marshalYAMLLines marshals a value to YAML with 2-space indentation and splits it into lines
+func marshalYAMLLines(value interface{}) ([]string, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render YAML: %w", err)
+	}
+	return strings.Split(strings.TrimRight(buf.String(), "\n"), "\n"), nil
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSidecars(t *testing.T) {
+	tests := []struct {
+		name        string
+		definition  string
+		wantNames   []string
+		wantVolumes []string
+		wantErr     bool
+	}{
+		{name: "empty", definition: "  \n"},
+		{
+			name:       "bare map of services",
+			definition: "redis:\n  image: redis:7\npostgres:\n  image: postgres:16\n",
+			wantNames:  []string{"postgres", "redis"},
+		},
+		{
+			name:        "compose document with volumes",
+			definition:  "services:\n  postgres:\n    image: postgres:16\nvolumes:\n  pgdata: {}\n",
+			wantNames:   []string{"postgres"},
+			wantVolumes: []string{"pgdata"},
+		},
+		{
+			name:       "build instead of image",
+			definition: "api:\n  build: ./api\n",
+			wantNames:  []string{"api"},
+		},
+		{name: "reserved name", definition: "devpod-workspace:\n  image: busybox\n", wantErr: true},
+		{name: "no image", definition: "redis:\n  command: redis-server\n", wantErr: true},
+		{name: "empty service", definition: "redis:\n", wantErr: true},
+		{name: "invalid YAML", definition: "redis: [", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := parseSidecars(tt.definition)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSidecars() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := sidecarNames(spec); !reflect.DeepEqual(got, tt.wantNames) {
+				t.Errorf("sidecar names = %v, want %v", got, tt.wantNames)
+			}
+			var volumes []string
+			if spec != nil {
+				for name := range spec.Volumes {
+					volumes = append(volumes, name)
+				}
+			}
+			if !reflect.DeepEqual(volumes, tt.wantVolumes) {
+				t.Errorf("volumes = %v, want %v", volumes, tt.wantVolumes)
+			}
+		})
+	}
+}
+
+func TestExtraServiceLines(t *testing.T) {
+	spec, err := parseSidecars("redis:\n  image: redis:7\n  restart: always\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines, err := extraServiceLines(spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"redis:",
+		"  image: redis:7",
+		"  networks:",
+		"    - workspace",
+		"  restart: always",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("extraServiceLines() = %q, want %q", lines, want)
+	}
+
+	proxies := map[string]map[string]interface{}{"redis": {"image": proxyImage}}
+	if _, err := extraServiceLines(spec, proxies); err == nil {
+		t.Error("sidecar conflicting with a proxy accepted")
+	}
+}
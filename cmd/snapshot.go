@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/s3"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// snapshotStagingDir holds the snapshot metadata inside the workspace container; it is archived
+// next to /workspace and extracted to the same place on restore
+const snapshotStagingDir = "run/devpod-snapshot"
+
+// ensureZstdScript installs zstd in workspaces whose image predates it
+const ensureZstdScript = `command -v zstd >/dev/null 2>&1 || { apt-get update -qq && apt-get install -y -qq zstd; } >&2`
+
+var (
+	snapshotImages     bool
+	snapshotS3Endpoint string
+	restoreClean       bool
+	restorePullImages  bool
+)
+
+// snapshotCmd represents the snapshot command
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot <file|s3://bucket/key|->",
+	Short: "Save the /workspace volume of a workspace",
+	Long: `Stream a zstd-compressed tar of /workspace from the workspace of the current machine
+(MACHINE_ID) over SSH to a local file, stdout (-) or an S3-compatible bucket.
+
+With --images the list of images in the inner Docker daemon is saved as well, so that
+restore can pull them again. S3 credentials are read from AWS_ACCESS_KEY_ID,
+AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_REGION.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSnapshot(args[0])
+	},
+}
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore <file|s3://bucket/key|->",
+	Short: "Seed the /workspace volume of a workspace from a snapshot",
+	Long: `Stream a snapshot created by the snapshot command into the workspace of the current
+machine (MACHINE_ID) over SSH. The workspace may be new or existing; files in the snapshot
+overwrite files in /workspace, and --clean empties /workspace first and then checks the number of
+files and bytes restored against the snapshot.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runRestore(args[0])
+	},
+}
+
+func init() {
+	snapshotCmd.Flags().BoolVar(&snapshotImages, "images", false, "also save the list of inner Docker images")
+	snapshotCmd.Flags().StringVar(&snapshotS3Endpoint, "s3-endpoint", "", "endpoint of an S3-compatible service (default: AWS S3 or AWS_ENDPOINT_URL)")
+	restoreCmd.Flags().BoolVar(&restoreClean, "clean", false, "empty /workspace before restoring")
+	restoreCmd.Flags().BoolVar(&restorePullImages, "pull-images", true, "pull the inner Docker images listed in the snapshot")
+	restoreCmd.Flags().StringVar(&snapshotS3Endpoint, "s3-endpoint", "", "endpoint of an S3-compatible service (default: AWS S3 or AWS_ENDPOINT_URL)")
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runSnapshot(destination string) error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	machineID, err := getMachineIDFromContext()
+	if err != nil {
+		return fmt.Errorf("failed to get machine ID: %w", err)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	sshClient, err := connectWorkspaceSSH(dokploy.NewClient(opts, logger), opts, machineID, logger)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	// Write to a temporary file first so that a failed snapshot never replaces a good one
+	var out *os.File
+	switch {
+	case destination == "-":
+		out = os.Stdout
+	case s3.IsURL(destination):
+		out, err = os.CreateTemp("", "devpod-snapshot-*.tar.zst")
+	default:
+		out, err = os.CreateTemp(filepath.Dir(destination), ".devpod-snapshot-*.tar.zst")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	if out != os.Stdout {
+		defer os.Remove(out.Name())
+		defer out.Close()
+	}
+
+	logger.Infof("Creating snapshot of workspace %s...", machineID)
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+	session.Stdout = out
+	session.Stderr = os.Stderr
+	if err := session.Run(snapshotScript(snapshotImages)); err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	if out == os.Stdout {
+		logger.Info("✓ Snapshot written to stdout")
+		return nil
+	}
+
+	info, err := out.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat snapshot: %w", err)
+	}
+
+	if s3.IsURL(destination) {
+		bucket, key, err := s3.ParseURL(destination)
+		if err != nil {
+			return err
+		}
+		s3Client, err := s3.NewClientFromEnv(snapshotS3Endpoint)
+		if err != nil {
+			return err
+		}
+		if _, err := out.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind snapshot: %w", err)
+		}
+		logger.Infof("Uploading %d MB to %s...", info.Size()>>20, destination)
+		if err := s3Client.PutObject(bucket, key, out, info.Size()); err != nil {
+			return err
+		}
+	} else {
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+		if err := os.Rename(out.Name(), destination); err != nil {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+	}
+
+	logger.Infof("✓ Snapshot of %s saved to %s (%d MB)", machineID, destination, info.Size()>>20)
+	return nil
+}
+
+func runRestore(source string) error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	machineID, err := getMachineIDFromContext()
+	if err != nil {
+		return fmt.Errorf("failed to get machine ID: %w", err)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	var in io.ReadCloser
+	switch {
+	case source == "-":
+		in = os.Stdin
+	case s3.IsURL(source):
+		bucket, key, err := s3.ParseURL(source)
+		if err != nil {
+			return err
+		}
+		s3Client, err := s3.NewClientFromEnv(snapshotS3Endpoint)
+		if err != nil {
+			return err
+		}
+		in, err = s3Client.GetObject(bucket, key)
+		if err != nil {
+			return err
+		}
+	default:
+		in, err = os.Open(source)
+		if err != nil {
+			return fmt.Errorf("failed to open snapshot: %w", err)
+		}
+	}
+	defer in.Close()
+
+	sshClient, err := connectWorkspaceSSH(dokploy.NewClient(opts, logger), opts, machineID, logger)
+	if err != nil {
+		return err
+	}
+	defer sshClient.Close()
+
+	logger.Infof("Restoring snapshot %s into workspace %s...", source, machineID)
+	session, err := sshClient.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer session.Close()
+	session.Stdin = in
+	session.Stdout = os.Stderr
+	session.Stderr = os.Stderr
+	if err := session.Run(restoreScript(restoreClean, restorePullImages)); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	logger.Infof("✓ Snapshot restored into workspace %s", machineID)
+	return nil
+}
+
+// workspaceManifestScript prints the number of regular files in /workspace and their total size
+const workspaceManifestScript = `find /workspace -type f -printf '%s\n' | awk '{ n++; s += $1 } END { printf "%.0f files, %.0f bytes\n", n, s }'`
+
+// snapshotScript writes a zstd-compressed tar of /workspace, and optionally the list of inner
+// Docker images, to stdout. The file count and size of /workspace are archived as well, so that
+// a clean restore can verify the copy.
+func snapshotScript(images bool) string {
+	script := []string{"set -eo pipefail", ensureZstdScript, "rm -rf /" + snapshotStagingDir, "mkdir -p /" + snapshotStagingDir}
+	if images {
+		script = append(script, "docker image ls --format '{{.Repository}}:{{.Tag}}' | grep -v '<none>' > /"+snapshotStagingDir+"/images.txt || true")
+	}
+	script = append(script,
+		workspaceManifestScript+" > /"+snapshotStagingDir+"/manifest.txt",
+		"tar -C / -cf - workspace "+snapshotStagingDir+" | zstd -q -T0 -3 -c")
+	return bashCommand(strings.Join(script, "\n"))
+}
+
+// restoreScript extracts a snapshot read from stdin into /workspace. After a clean restore the
+// files are compared with the manifest of the snapshot.
+func restoreScript(clean, pullImages bool) string {
+	script := []string{"set -eo pipefail", ensureZstdScript, "rm -rf /" + snapshotStagingDir}
+	if clean {
+		script = append(script, "find /workspace -mindepth 1 -delete")
+	}
+	script = append(script, "zstd -q -d -c | tar -C / -xf -")
+	if clean {
+		script = append(script,
+			"if [ -f /"+snapshotStagingDir+"/manifest.txt ]; then",
+			"  expected=$(cat /"+snapshotStagingDir+"/manifest.txt)",
+			"  restored=$("+workspaceManifestScript+")",
+			"  if [ \"$restored\" != \"$expected\" ]; then",
+			"    echo \"error: restored $restored into /workspace, but the snapshot has $expected\" >&2",
+			"    exit 1",
+			"  fi",
+			"  echo \"Verified /workspace: $restored\" >&2",
+			"fi")
+	}
+	if pullImages {
+		script = append(script,
+			"if [ -s /"+snapshotStagingDir+"/images.txt ]; then",
+			"  while read -r image; do docker pull -q \"$image\" >&2 || echo \"warning: failed to pull $image\" >&2; done < /"+snapshotStagingDir+"/images.txt",
+			"fi")
+	}
+	return bashCommand(strings.Join(script, "\n"))
+}
+
+// bashCommand runs a script with bash, whatever the login shell of the workspace user is
+func bashCommand(script string) string {
+	return "bash -c '" + strings.ReplaceAll(script, "'", `'\''`) + "'"
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestBashCommand(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{name: "plain", script: "echo workspace", want: "workspace\n"},
+		{name: "single quotes", script: "echo 'it'\"'\"'s' '{{.Repository}}'", want: "it's {{.Repository}}\n"},
+		{name: "pipefail", script: "set -eo pipefail\nfalse | cat\necho not reached", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// SSH hands the command to the login shell of the workspace user
+			output, _ := exec.Command("sh", "-c", bashCommand(tt.script)).Output()
+			if string(output) != tt.want {
+				t.Errorf("output = %q, want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotScripts(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+
+	scripts := map[string]string{
+		"snapshot":             snapshotScript(false),
+		"snapshot with images": snapshotScript(true),
+		"restore":              restoreScript(false, false),
+		"clean restore":        restoreScript(true, true),
+		"inner Docker":         bashCommand(innerDockerScript),
+	}
+	for name, script := range scripts {
+		t.Run(name, func(t *testing.T) {
+			if !strings.Contains(script, "set -eo pipefail") {
+				t.Errorf("script does not fail on pipe errors:\n%s", script)
+			}
+			// Check the syntax of the script bash runs, without running it
+			check := strings.Replace(script, "bash -c", "bash -n -c", 1)
+			if output, err := exec.Command("sh", "-c", check).CombinedOutput(); err != nil {
+				t.Errorf("invalid script: %v\n%s\n%s", err, output, script)
+			}
+		})
+	}
+
+	if !strings.Contains(restoreScript(true, false), "manifest.txt") {
+		t.Error("clean restore does not verify the manifest")
+	}
+	if strings.Contains(restoreScript(false, false), "manifest.txt") {
+		t.Error("restore without --clean verifies the manifest")
+	}
+}
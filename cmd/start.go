@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
@@ -9,18 +12,21 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var startTimeout time.Duration
+
 // startCmd represents the start command
 var startCmd = &cobra.Command{
 	Use:   "start",
 	Short: "Start a stopped Dokploy workspace",
 	Long: `Start a previously stopped development workspace in Dokploy.
-This will restart the Docker Compose service.`,
+This will restart the Docker Compose service and wait until the workspace accepts SSH connections.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runStart()
 	},
 }
 
 func init() {
+	startCmd.Flags().DurationVar(&startTimeout, "timeout", 0, "how long to wait for the workspace to become ready (default DOKPLOY_START_TIMEOUT or 5m)")
 	rootCmd.AddCommand(startCmd)
 }
 
@@ -44,15 +50,82 @@ func runStart() error {
 		return fmt.Errorf("failed to load options: %w", err)
 	}
 
+	timeout := opts.StartTimeout
+	if startTimeout > 0 {
+		timeout = startTimeout
+	}
+
 	// Create Dokploy client
 	client := dokploy.NewClient(opts, logger)
 
-	// Start the Docker Compose service
-	err = client.StartComposeByName(machineID)
+	compose, err := client.GetComposeByName(machineID)
 	if err != nil {
-		return fmt.Errorf("failed to start Docker Compose service: %w", err)
+		return fmt.Errorf("failed to find Docker Compose service: %w", err)
+	}
+
+	upgraded := false
+	if opts.AutoUpgrade {
+		if upgraded, err = autoUpgradeWorkspace(client, opts, compose.ComposeID, timeout, logger); err != nil {
+			return err
+		}
+	}
+
+	// Start the Docker Compose service; an upgrade already redeployed it
+	if !upgraded {
+		err = client.StartCompose(compose.ComposeID)
+		if err != nil {
+			return fmt.Errorf("failed to start Docker Compose service: %w", err)
+		}
+	}
+
+	logger.Infof("Waiting up to %v for the workspace to become ready...", timeout)
+	if err := waitForWorkspaceReady(client, compose.ComposeID, opts, timeout, logger); err != nil {
+		return fmt.Errorf("workspace %s did not become ready: %w", machineID, err)
 	}
 
 	logger.Info("✓ Dokploy workspace started (Docker Compose service)")
 	return nil
-} 
\ No newline at end of file
+}
+
+// autoUpgradeWorkspace upgrades a workspace rendered from an older template before it is
+// started (DOKPLOY_AUTO_UPGRADE). It reports whether the workspace was redeployed. Workspaces
+// whose upgrade is refused, e.g. legacy ones with anonymous volumes, are started unchanged.
+func autoUpgradeWorkspace(dokployClient *dokploy.Client, opts *options.Options, composeID string, timeout time.Duration, logger *logrus.Logger) (bool, error) {
+	compose, err := dokployClient.GetCompose(composeID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get compose service: %w", err)
+	}
+	version, outdated, err := workspaceTemplateVersion(compose)
+	if err != nil || !outdated {
+		return false, err
+	}
+
+	logger.Infof("Workspace uses template version %d, upgrading to %d (DOKPLOY_AUTO_UPGRADE=true)", version, templateVersion)
+	changed, err := upgradeWorkspace(dokployClient, opts, compose, false, os.Stderr, timeout, logger)
+	if errors.Is(err, errUpgradeRefused) {
+		// Starting matters more than upgrading; the workspace can still be upgraded by hand
+		logger.Warnf("%v; starting the workspace unchanged", err)
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to upgrade workspace: %w", err)
+	}
+	return changed, nil
+}
+
+// workspaceTemplateVersion returns the template version a workspace was rendered from and whether
+// it is older than the current one. Provider-owned workspaces without a marker predate template
+// versions and count as version 0; adopted services are never outdated.
+func workspaceTemplateVersion(compose *dokploy.Compose) (int, bool, error) {
+	marker, err := parseWorkspaceMarker(compose.ComposeFile)
+	if err != nil {
+		return 0, false, err
+	}
+	if marker == nil {
+		return 0, isProviderOwned(compose, compose.Name), nil
+	}
+	if marker.Adopted {
+		return marker.TemplateVersion, false, nil
+	}
+	return marker.TemplateVersion, marker.TemplateVersion < templateVersion, nil
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+)
+
+func TestWorkspaceTemplateVersion(t *testing.T) {
+	tests := []struct {
+		name         string
+		compose      dokploy.Compose
+		wantVersion  int
+		wantOutdated bool
+		wantErr      bool
+	}{
+		{
+			name:        "current template",
+			compose:     dokploy.Compose{Name: "ws", ComposeFile: fmt.Sprintf("x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n  template-version: %d\n", templateVersion)},
+			wantVersion: templateVersion,
+		},
+		{
+			name:         "older template",
+			compose:      dokploy.Compose{Name: "ws", ComposeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n  template-version: 1\n"},
+			wantVersion:  1,
+			wantOutdated: true,
+		},
+		{
+			name:         "marker without version",
+			compose:      dokploy.Compose{Name: "ws", ComposeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n"},
+			wantOutdated: true,
+		},
+		{
+			name:         "legacy workspace without marker",
+			compose:      dokploy.Compose{Name: "ws", ComposeFile: "services: {}\n", Description: legacyDescriptionPrefix + " 2024-01-01"},
+			wantOutdated: true,
+		},
+		{name: "foreign service without marker", compose: dokploy.Compose{Name: "ws", ComposeFile: "services: {}\n", Description: "my app"}},
+		{
+			name:    "adopted service",
+			compose: dokploy.Compose{Name: "ws", ComposeFile: "x-devpod:\n  managed-by: dokploy-devpod-provider\n  machine-id: ws\n  adopted: true\n"},
+		},
+		{name: "invalid compose file", compose: dokploy.Compose{Name: "ws", ComposeFile: "x-devpod: ["}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, outdated, err := workspaceTemplateVersion(&tt.compose)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("workspaceTemplateVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if version != tt.wantVersion || outdated != tt.wantOutdated {
+				t.Errorf("workspaceTemplateVersion() = %d, %v, want %d, %v", version, outdated, tt.wantVersion, tt.wantOutdated)
+			}
+		})
+	}
+}
+
+// baselineCompose is a workspace rendered by the first provider version: no marker, an anonymous
+// Docker volume and a bind-mounted /workspace
+const baselineCompose = `version: "3.8"
+
+services:
+  devpod-workspace:
+    image: cruizba/ubuntu-dind:latest
+    privileged: true
+    restart: unless-stopped
+    ports:
+      - "2230:22"
+    networks:
+      - dokploy-network
+    environment:
+      - DOCKER_TLS_CERTDIR=
+      - DOCKER_DRIVER=overlay2
+      - DEVPOD_WORKSPACE=true
+      - SSH_PUBLIC_KEY=ssh-ed25519 AAAAC3Nza devpod
+    volumes:
+      - /var/lib/docker
+      - ./workspace-data:/workspace
+    command: >
+      bash -c "echo setup"
+
+networks:
+  dokploy-network:
+    external: true
+`
+
+func TestAutoUpgradeWorkspace(t *testing.T) {
+	fake := &fakeDokploy{composes: map[string]*dokploy.Compose{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	opts := testOptions()
+	opts.DokployServerURL = server.URL
+
+	// versionOneCompose renders the workspace like template version 1, without recorded settings
+	versionOneCompose := func(opts *options.Options) string {
+		params, err := buildComposeParams(opts, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		params.Settings = nil
+		params.MachineID = "ws"
+		params.SSHPort = 2230
+		params.SSHPublicKey = "ssh-ed25519 AAAAC3Nza devpod"
+		params.ComposeID = "compose-1"
+		file, err := generateDockerCompose(params, testLogger())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Replace(file, fmt.Sprintf("template-version: %d", templateVersion), "template-version: 1", 1)
+	}
+	withSidecar := *opts
+	withSidecar.Sidecars = "services:\n  redis:\n    image: redis:7\n"
+
+	tests := []struct {
+		name         string
+		compose      dokploy.Compose
+		wantUpgraded bool
+		wantPosts    []string
+	}{
+		{
+			name:    "baseline template loses data",
+			compose: dokploy.Compose{Description: legacyDescriptionPrefix + " 2024-01-01", ComposeFile: baselineCompose},
+		},
+		{
+			name:    "settings differ from the current options",
+			compose: dokploy.Compose{ComposeFile: versionOneCompose(&withSidecar)},
+		},
+		{
+			name:         "settings match the current options",
+			compose:      dokploy.Compose{ComposeFile: versionOneCompose(opts)},
+			wantUpgraded: true,
+			wantPosts:    []string{"/api/compose.update", "/api/compose.deploy"},
+		},
+		{
+			name:    "current template",
+			compose: dokploy.Compose{ComposeFile: renderTestCompose(t, opts)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compose := tt.compose
+			compose.ComposeID = "compose-1"
+			compose.Name = "ws"
+			compose.Status = "idle"
+			fake.composes["compose-1"] = &compose
+			fake.takePosts()
+
+			upgraded, err := autoUpgradeWorkspace(dokploy.NewClient(opts, testLogger()), opts, "compose-1", 10*time.Second, testLogger())
+			if err != nil {
+				t.Fatalf("autoUpgradeWorkspace() error = %v, want the workspace to start", err)
+			}
+			if upgraded != tt.wantUpgraded {
+				t.Errorf("autoUpgradeWorkspace() = %v, want %v", upgraded, tt.wantUpgraded)
+			}
+			if posts := fake.takePosts(); !reflect.DeepEqual(posts, tt.wantPosts) {
+				t.Errorf("autoUpgradeWorkspace() made POST calls %v, want %v", posts, tt.wantPosts)
+			}
+			if tt.wantUpgraded {
+				if marker, err := parseWorkspaceMarker(compose.ComposeFile); err != nil || marker == nil || marker.Settings == nil {
+					t.Errorf("upgraded workspace has no recorded settings: %v, %v", marker, err)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -116,6 +117,33 @@ func runStatus() error {
 		return nil
 	}
 
+	// Use the container healthcheck (sshd + dockerd) when available
+	health, progress, err := getWorkspaceHealth(dokployClient, compose, logger)
+	if err != nil {
+		logger.Debugf("Failed to get workspace container health, falling back to SSH probing: %v", err)
+	} else {
+		// The setup reports its progress through the healthcheck until the workspace is ready
+		if progress != nil {
+			if progress.Failed() {
+				return progress.Err()
+			}
+			logger.Infof("Busy: %s", progress)
+			fmt.Println(client.StatusBusy)
+			return nil
+		}
+
+		switch health {
+		case client.HealthStarting:
+			logger.Debugf("Workspace container is still starting (healthcheck: starting) - returning Busy")
+			fmt.Println(client.StatusBusy)
+			return nil
+		case client.HealthUnhealthy:
+			// Does not recover on its own; fail instead of keeping DevPod polling a Busy workspace
+			return fmt.Errorf("workspace container %s is unhealthy: sshd or dockerd stopped responding, or a post-setup hook failed. "+
+				"Check the container logs in the Dokploy dashboard or redeploy the workspace", machineID)
+		}
+	}
+
 	// For Docker Compose, the port mapping is embedded in the compose file
 	// We need to extract it from the compose configuration
 	// Since we know we set the port in create.go, we can try to derive it
@@ -132,14 +160,12 @@ func runStatus() error {
 		return nil
 	}
 
-	// Extract host from server URL
-	parsedURL, err := url.Parse(opts.DokployServerURL)
-	if err != nil {
-		logger.Debugf("Failed to parse server URL: %v", err)
+	sshHost := workspaceSSHHost(compose, opts)
+	if sshHost == "" {
+		logger.Debugf("Failed to determine the SSH host of the workspace")
 		fmt.Println(client.StatusBusy)
 		return nil
 	}
-	sshHost := strings.Split(parsedURL.Host, ":")[0]
 	logger.Debugf("SSH connection target: %s:%d", sshHost, sshPort)
 
 	// Check SSH readiness
@@ -157,7 +183,61 @@ func runStatus() error {
 	return nil
 }
 
+// postSetupFailedOutput starts the healthcheck output when a post-setup hook failed
+const postSetupFailedOutput = "post-setup hook failed:"
+
+// getWorkspaceHealth returns the healthcheck status of the workspace container of a compose service,
+// along with the setup progress while the setup has not finished
+func getWorkspaceHealth(dokployClient *dokploy.Client, compose *dokploy.Compose, logger *logrus.Logger) (client.Health, *client.SetupProgress, error) {
+	container, err := workspaceContainer(dokployClient, compose)
+	if err != nil {
+		return client.HealthNone, nil, err
+	}
+
+	inspect, err := dokployClient.InspectContainer(container.ContainerID, compose.ServerID)
+	if err != nil {
+		return client.HealthNone, nil, err
+	}
+
+	health := inspect.Health()
+	logger.Debugf("Workspace container %s: state=%s health=%s", container.Name, inspect.State.Status, health)
+
+	// Health and progress of a container that is not running are left over from its previous run
+	if inspect.State.Status != "running" {
+		return client.HealthNone, nil, nil
+	}
+
+	progress := inspect.SetupProgress()
+	if progress != nil {
+		logger.Debugf("Workspace setup progress: %s [%s]", progress, progress.Status)
+	}
+
+	// A failed post-setup hook never recovers on its own, so don't wait for the start period to end
+	if health == client.HealthStarting && strings.HasPrefix(inspect.LastHealthOutput(), postSetupFailedOutput) {
+		health = client.HealthUnhealthy
+	}
+	if health == client.HealthUnhealthy && progress == nil {
+		if output := inspect.LastHealthOutput(); output != "" {
+			logger.Errorf("Last healthcheck output: %s", output)
+		}
+	}
+
+	return health, progress, nil
+}
+
 func extractSSHPortFromCompose(compose *dokploy.Compose, opts *options.Options, logger *logrus.Logger) (int, error) {
+	// Workspaces carry their SSH port in the x-devpod marker of the compose file
+	if marker, err := parseWorkspaceMarker(compose.ComposeFile); err == nil && marker != nil && marker.SSHPort > 0 {
+		logger.Debugf("SSH port from workspace marker: %d", marker.SSHPort)
+		return marker.SSHPort, nil
+	}
+
+	// Workspaces created before the marker existed publish it in the ports of the workspace service
+	if _, port, err := findSSHService(compose.ComposeFile, workspaceServiceName); err == nil {
+		logger.Debugf("SSH port from the workspace service: %d", port)
+		return port, nil
+	}
+
 	// For Docker Compose services, we need to find the SSH port from the existing services
 	// Since we know the port was allocated during creation, we can check all projects for used ports
 	// and find the one that matches our naming pattern
@@ -174,7 +254,7 @@ func extractSSHPortFromCompose(compose *dokploy.Compose, opts *options.Options,
 		for _, projectCompose := range project.Composes {
 			if projectCompose.ComposeID == compose.ComposeID {
 				// This is our compose service
-				// The SSH port was allocated in the range 2222-2250
+				// The SSH port was allocated in the range sshPortRangeStart-sshPortRangeEnd
 				// We need to check each port to see which one is in use
 				
 				parsedURL, err := url.Parse(opts.DokployServerURL)
@@ -184,8 +264,8 @@ func extractSSHPortFromCompose(compose *dokploy.Compose, opts *options.Options,
 				sshHost := strings.Split(parsedURL.Host, ":")[0]
 				
 				// Check ports in the range we use
-				for port := 2222; port <= 2250; port++ {
-					testAddress := fmt.Sprintf("%s:%d", sshHost, port)
+				for port := sshPortRangeStart; port <= sshPortRangeEnd; port++ {
+					testAddress := net.JoinHostPort(sshHost, strconv.Itoa(port))
 					conn, err := net.DialTimeout("tcp", testAddress, 1*time.Second)
 					if err == nil {
 						conn.Close()
@@ -229,7 +309,7 @@ func isSSHPort(host string, port int, logger *logrus.Logger) bool {
 }
 
 func checkSSHReadiness(host string, port int, logger *logrus.Logger) bool {
-	testAddress := fmt.Sprintf("%s:%d", host, port)
+	testAddress := net.JoinHostPort(host, strconv.Itoa(port))
 	
 	// First check if the port is accessible
 	conn, err := net.DialTimeout("tcp", testAddress, 3*time.Second)
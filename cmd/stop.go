@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
 	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
@@ -9,18 +10,21 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var stopTimeout time.Duration
+
 // stopCmd represents the stop command
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop a running Dokploy workspace",
 	Long: `Stop a currently running development workspace in Dokploy.
-This will stop the Docker Compose service but preserve the workspace.`,
+This will stop the Docker Compose service but preserve the workspace, and wait until the container is down.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return runStop()
 	},
 }
 
 func init() {
+	stopCmd.Flags().DurationVar(&stopTimeout, "timeout", 0, "how long to wait for the workspace to stop (default DOKPLOY_STOP_TIMEOUT or 2m)")
 	rootCmd.AddCommand(stopCmd)
 }
 
@@ -44,15 +48,30 @@ func runStop() error {
 		return fmt.Errorf("failed to load options: %w", err)
 	}
 
+	timeout := opts.StopTimeout
+	if stopTimeout > 0 {
+		timeout = stopTimeout
+	}
+
 	// Create Dokploy client
 	client := dokploy.NewClient(opts, logger)
 
+	compose, err := client.GetComposeByName(machineID)
+	if err != nil {
+		return fmt.Errorf("failed to find Docker Compose service: %w", err)
+	}
+
 	// Stop the Docker Compose service
-	err = client.StopComposeByName(machineID)
+	err = client.StopCompose(compose.ComposeID)
 	if err != nil {
 		return fmt.Errorf("failed to stop Docker Compose service: %w", err)
 	}
 
+	logger.Infof("Waiting up to %v for the workspace to stop...", timeout)
+	if err := waitForWorkspaceStopped(client, compose.ComposeID, timeout, logger); err != nil {
+		return fmt.Errorf("workspace %s did not stop: %w", machineID, err)
+	}
+
 	logger.Info("✓ Dokploy workspace stopped (Docker Compose service)")
 	return nil
-} 
\ No newline at end of file
+}
@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// maxDiffLineLength truncates long lines (e.g. the encoded setup script) in upgrade diffs
+const maxDiffLineLength = 160
+
+// errUpgradeRefused is returned when upgrading a workspace would lose data or change its settings
+var errUpgradeRefused = errors.New("refusing to upgrade workspace")
+
+// sshPublicKeyPattern finds the authorized key in the environment of a workspace compose file
+var sshPublicKeyPattern = regexp.MustCompile(`(?m)^\s*-\s*SSH_PUBLIC_KEY=(.+)$`)
+
+var (
+	upgradeAll     bool
+	upgradeDryRun  bool
+	upgradeTimeout time.Duration
+)
+
+// upgradeCmd represents the upgrade command
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Upgrade workspaces to the current compose template",
+	Long: `Render the compose file of the workspace of the current machine (MACHINE_ID) again with the
+templates of this provider version, show the difference, upload it and redeploy.
+
+The SSH port, SSH keys, machine type, isolation, image and named volumes of the workspace are
+kept, and so are the sidecars, networks, inner Docker daemon options, hooks and inactivity
+settings recorded in its x-devpod block, whatever the current options are. Workspaces created
+before settings were recorded are only upgraded when the current options render the same
+settings. With --all every workspace in DOKPLOY_PROJECT_NAME is upgraded. Stopped workspaces are
+stopped again after the redeploy.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runUpgrade()
+	},
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradeAll, "all", false, "upgrade every workspace in the project")
+	upgradeCmd.Flags().BoolVar(&upgradeDryRun, "dry-run", false, "only show the differences")
+	upgradeCmd.Flags().DurationVar(&upgradeTimeout, "timeout", 0, "how long to wait for each redeploy (default: DOKPLOY_START_TIMEOUT)")
+	rootCmd.AddCommand(upgradeCmd)
+}
+
+func runUpgrade() error {
+	// Setup logger
+	logger := logrus.New()
+	if verbose {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	// Load options from environment
+	opts, err := options.LoadFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to load options: %w", err)
+	}
+
+	timeout := opts.StartTimeout
+	if upgradeTimeout > 0 {
+		timeout = upgradeTimeout
+	}
+
+	dokployClient := dokploy.NewClient(opts, logger)
+
+	var machineIDs []string
+	if upgradeAll {
+		workspaces, err := listWorkspaces(dokployClient, opts, []string{opts.DokployProjectName}, logger)
+		if err != nil {
+			return err
+		}
+		for _, workspace := range workspaces {
+			machineIDs = append(machineIDs, workspace.MachineID)
+		}
+		if len(machineIDs) == 0 {
+			logger.Infof("No workspaces found in project '%s'", opts.DokployProjectName)
+			return nil
+		}
+	} else {
+		machineID, err := getMachineIDFromContext()
+		if err != nil {
+			return fmt.Errorf("failed to get machine ID: %w", err)
+		}
+		machineIDs = []string{machineID}
+	}
+
+	projectID, err := findProjectID(dokployClient, opts.DokployProjectName)
+	if err != nil {
+		return err
+	}
+	if projectID == "" {
+		return fmt.Errorf("project '%s' not found", opts.DokployProjectName)
+	}
+
+	var failed []string
+	for _, machineID := range machineIDs {
+		compose, marker, err := findExistingWorkspace(dokployClient, projectID, machineID)
+		if err == nil && compose == nil {
+			err = fmt.Errorf("workspace %s not found in project '%s'", machineID, opts.DokployProjectName)
+		}
+		if err == nil && marker != nil && marker.Adopted {
+			logger.Infof("Skipping workspace %s: adopted services keep their own compose file", machineID)
+			continue
+		}
+		if err == nil {
+			var changed bool
+			changed, err = upgradeWorkspace(dokployClient, opts, compose, upgradeDryRun, os.Stdout, timeout, logger)
+			if err == nil && changed && !upgradeDryRun {
+				// A deploy starts the workspace; put stopped workspaces back the way they were
+				err = restoreStopped(dokployClient, opts, compose, logger)
+			}
+			switch {
+			case err != nil:
+			case !changed:
+				logger.Infof("✓ Workspace %s is up to date", machineID)
+			case upgradeDryRun:
+				logger.Infof("Workspace %s would be upgraded (dry run)", machineID)
+			default:
+				logger.Infof("✓ Workspace %s upgraded", machineID)
+			}
+		}
+		if err != nil {
+			if !upgradeAll {
+				return err
+			}
+			logger.Errorf("Failed to upgrade workspace %s: %v", machineID, err)
+			failed = append(failed, machineID)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to upgrade %d of %d workspaces: %s", len(failed), len(machineIDs), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// upgradeWorkspace renders the compose file of a provider-owned workspace (with its compose file
+// loaded) from the current templates and redeploys it when it changed. The workspace settings
+// recorded in the x-devpod marker win over the current options. The diff is written to out.
+// Upgrades that would lose data or change the settings fail with errUpgradeRefused.
+func upgradeWorkspace(dokployClient *dokploy.Client, opts *options.Options, compose *dokploy.Compose, dryRun bool, out io.Writer, timeout time.Duration, logger *logrus.Logger) (bool, error) {
+	machineID := compose.Name
+	marker, err := parseWorkspaceMarker(compose.ComposeFile)
+	if err != nil {
+		return false, err
+	}
+
+	workspaceOpts, recorded, err := workspaceOptions(opts, compose)
+	if err != nil {
+		return false, err
+	}
+	if recorded {
+		if current, err := settingsFromOptions(opts); err == nil {
+			if names := marker.Settings.differences(current); len(names) > 0 {
+				logger.Infof("Workspace %s keeps its own settings, which differ from the current options: %s", machineID, strings.Join(names, ", "))
+			}
+		}
+	}
+	params, err := buildComposeParams(workspaceOpts, logger)
+	if err != nil {
+		return false, err
+	}
+
+	sshPort, err := extractSSHPortFromCompose(compose, opts, logger)
+	if err != nil || sshPort == 0 {
+		return false, fmt.Errorf("SSH port of workspace %s not found: %v", machineID, err)
+	}
+
+	publicKey, err := composeSSHPublicKey(compose, machineID, logger)
+	if err != nil {
+		return false, err
+	}
+
+	params.MachineID = machineID
+	if marker != nil {
+		params.SSHHost = marker.SSHHost
+	}
+	params.SSHPort = sshPort
+	params.SSHPublicKey = publicKey
+	params.ComposeID = compose.ComposeID
+	dockerComposeContent, err := generateDockerCompose(params, logger)
+	if err != nil {
+		return false, fmt.Errorf("failed to generate Docker Compose configuration: %w", err)
+	}
+
+	env := inactivityComposeEnv(workspaceOpts)
+	if dockerComposeContent == compose.ComposeFile && env == compose.Env {
+		return false, nil
+	}
+
+	if err := checkVolumesPreserved(compose.ComposeFile, dockerComposeContent); err != nil {
+		return false, fmt.Errorf("%w %s: %w", errUpgradeRefused, machineID, err)
+	}
+	if !recorded {
+		if err := checkSettingsPreserved(compose.ComposeFile, dockerComposeContent); err != nil {
+			return false, fmt.Errorf("%w %s: %w", errUpgradeRefused, machineID, err)
+		}
+	}
+
+	fmt.Fprintf(out, "--- %s (current)\n+++ %s (template version %d)\n", machineID, machineID, templateVersion)
+	writeLineDiff(out, compose.ComposeFile, dockerComposeContent)
+	if env != compose.Env {
+		fmt.Fprintln(out, "~ compose environment changes")
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	logger.Infof("Upgrading workspace %s to template version %d...", machineID, templateVersion)
+	if err := dokployClient.SaveComposeFile(dokploy.SaveComposeFileRequest{
+		ComposeID:     compose.ComposeID,
+		DockerCompose: dockerComposeContent,
+		Env:           env,
+	}); err != nil {
+		return false, fmt.Errorf("failed to save Docker Compose file: %w", err)
+	}
+
+	if err := redeployCompose(dokployClient, compose, timeout, logger); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// markerOptions returns the options with the settings a workspace was created with, as recorded
+// in its marker, taking precedence
+func markerOptions(opts *options.Options, marker *workspaceMarker) (*options.Options, error) {
+	workspaceOpts := *opts
+	if marker != nil {
+		if marker.MachineType != "" {
+			workspaceOpts.MachineType = marker.MachineType
+		}
+		if marker.Isolation != "" {
+			workspaceOpts.Isolation = marker.Isolation
+		}
+		if marker.Image != "" {
+			workspaceOpts.WorkspaceImage = marker.Image
+		}
+		if marker.Settings != nil {
+			if err := marker.Settings.apply(&workspaceOpts); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &workspaceOpts, nil
+}
+
+// restoreStopped stops a workspace again that was stopped before an upgrade redeployed it
+func restoreStopped(dokployClient *dokploy.Client, opts *options.Options, compose *dokploy.Compose, logger *logrus.Logger) error {
+	if status, _ := dokploy.MapComposeStatus(compose.Status); status != client.StatusStopped {
+		return nil
+	}
+	if err := dokployClient.StopCompose(compose.ComposeID); err != nil {
+		return fmt.Errorf("failed to stop Docker Compose service: %w", err)
+	}
+	return waitForWorkspaceStopped(dokployClient, compose.ComposeID, opts.StopTimeout, logger)
+}
+
+// composeSSHPublicKey returns the authorized key of a workspace from its compose file, falling
+// back to the key in the local machine folder
+func composeSSHPublicKey(compose *dokploy.Compose, machineID string, logger *logrus.Logger) (string, error) {
+	if match := sshPublicKeyPattern.FindStringSubmatch(compose.ComposeFile); match != nil {
+		publicKey := strings.ReplaceAll(strings.TrimSpace(match[1]), `\"`, `"`)
+		if publicKey != "" && !strings.Contains(publicKey, "PLACEHOLDER") {
+			return publicKey, nil
+		}
+	}
+
+	machineFolder, err := findMachineFolder(machineID)
+	if err != nil {
+		return "", err
+	}
+	return loadMachinePublicKey(machineFolder, logger)
+}
+
+// writeLineDiff writes a minimal line diff of two files, prefixing removed lines with "-" and
+// added lines with "+"; unchanged lines are left out
+func writeLineDiff(out io.Writer, before, after string) {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// Longest common subsequence table, filled from the end
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintln(out, "-"+truncateDiffLine(a[i]))
+			i++
+		default:
+			fmt.Fprintln(out, "+"+truncateDiffLine(b[j]))
+			j++
+		}
+	}
+}
+
+func truncateDiffLine(line string) string {
+	if len(line) <= maxDiffLineLength {
+		return line
+	}
+	return line[:maxDiffLineLength] + "…"
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/dokploy"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	devpodconfig "github.com/loft-sh/devpod/pkg/config"
+	"github.com/loft-sh/devpod/pkg/ssh"
+	"github.com/sirupsen/logrus"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+// connectWorkspaceSSH opens an SSH connection as root to the workspace of a machine, using the
+// machine's DevPod key. It is meant for provider subcommands run outside of DevPod.
+func connectWorkspaceSSH(dokployClient *dokploy.Client, opts *options.Options, machineID string, logger *logrus.Logger) (*cryptossh.Client, error) {
+	composeBasic, err := dokployClient.GetComposeByName(machineID)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s not found: %w", machineID, err)
+	}
+	compose, err := dokployClient.GetCompose(composeBasic.ComposeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compose service: %w", err)
+	}
+	return connectComposeSSH(compose, opts, machineID, logger)
+}
+
+// connectComposeSSH opens an SSH connection as root to a workspace compose service (with its
+// compose file loaded), using the DevPod key of the machine
+func connectComposeSSH(compose *dokploy.Compose, opts *options.Options, machineID string, logger *logrus.Logger) (*cryptossh.Client, error) {
+	sshPort, err := extractSSHPortFromCompose(compose, opts, logger)
+	if err != nil || sshPort == 0 {
+		return nil, fmt.Errorf("SSH port of workspace %s not found: %v", machineID, err)
+	}
+
+	machineFolder, err := findMachineFolder(machineID)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := ssh.GetPrivateKeyRawBase(machineFolder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	address := net.JoinHostPort(workspaceSSHHost(compose, opts), strconv.Itoa(sshPort))
+
+	logger.Debugf("Connecting to workspace %s at %s", machineID, address)
+	sshClient, err := ssh.NewSSHClient("root", address, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to workspace %s: %w", machineID, err)
+	}
+	return sshClient, nil
+}
+
+// findMachineFolder returns MACHINE_FOLDER when DevPod set it, otherwise the machine's folder
+// in the local DevPod directory
+func findMachineFolder(machineID string) (string, error) {
+	if machineFolder := os.Getenv("MACHINE_FOLDER"); machineFolder != "" {
+		return machineFolder, nil
+	}
+
+	configDir, err := devpodconfig.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the DevPod directory: %w", err)
+	}
+	matches, err := filepath.Glob(filepath.Join(configDir, "contexts", "*", "machines", machineID))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up machine %s: %w", machineID, err)
+	}
+	if len(matches) == 0 {
+		return "", fmt.Errorf("machine %s not found in %s; set MACHINE_FOLDER to the folder holding its SSH key", machineID, configDir)
+	}
+	return matches[0], nil
+}
@@ -22,10 +22,40 @@ optionGroups:
       - DOKPLOY_API_TOKEN
     name: "Dokploy Configuration"
     defaultVisible: true
+  - options:
+      - MACHINE_TYPE
+      - DOKPLOY_MACHINE_TYPES_FILE
+      - DOKPLOY_MACHINE_TYPES
+      - DOKPLOY_ISOLATION
+      - DOKPLOY_WORKSPACE_IMAGE
+      - DOKPLOY_SIDECARS
+      - DOKPLOY_POST_SETUP_SCRIPT
+      - DOKPLOY_DOTFILES_URL
+    name: "Workspace Resources"
+    defaultVisible: true
   - options:
       - DOKPLOY_PROJECT_NAME
+      - DOKPLOY_RETAIN_VOLUMES_ON_DELETE
+      - DOKPLOY_START_TIMEOUT
+      - DOKPLOY_STOP_TIMEOUT
+      - DOKPLOY_AUTO_UPGRADE
+      - DOKPLOY_INACTIVITY_TIMEOUT
+      - DOKPLOY_INACTIVITY_API_TOKEN
+      - DOKPLOY_JOIN_DOKPLOY_NETWORK
+      - DOKPLOY_ALLOWED_SERVICES
     name: "Advanced Configuration"
     defaultVisible: false
+  - options:
+      - DOKPLOY_DOCKER_REGISTRY_MIRRORS
+      - DOKPLOY_DOCKER_INSECURE_REGISTRIES
+      - DOKPLOY_DOCKER_ADDRESS_POOL
+      - DOKPLOY_DOCKER_MTU
+      - DOKPLOY_DOCKER_LOG_MAX_SIZE
+      - DOKPLOY_DOCKER_LOG_MAX_FILE
+      - DOKPLOY_DOCKER_STORAGE_DRIVER
+      - DOKPLOY_SHARED_CACHE
+    name: "Inner Docker Daemon"
+    defaultVisible: false
 options:
   DOKPLOY_SERVER_URL:
     description: The URL of your Dokploy server (e.g., https://dokploy.example.com)
@@ -40,6 +70,86 @@ options:
   DOKPLOY_PROJECT_NAME:
     description: Dokploy project name for DevPod workspaces
     default: "devpod-workspaces"
+  MACHINE_TYPE:
+    description: "Machine type that sets CPU, memory and process limits for the workspace container: a built-in type (by default small: 2 CPUs, 4 GB RAM, medium: 4 CPUs, 8 GB RAM, large: 8 CPUs, 16 GB RAM) or a type defined or overridden in DOKPLOY_MACHINE_TYPES(_FILE)"
+    default: "small"
+    suggestions:
+      - small
+      - medium
+      - large
+  DOKPLOY_MACHINE_TYPES_FILE:
+    description: Path to a JSON file with additional or overridden machine types
+  DOKPLOY_MACHINE_TYPES:
+    description: 'Inline JSON with additional or overridden machine types, e.g. {"small": {"cpus": "1", "memory": "2g"}}'
+  DOKPLOY_ISOLATION:
+    description: How Docker-in-Docker is isolated. sysbox and rootless avoid privileged containers
+    default: "privileged"
+    enum:
+      - value: privileged
+        displayName: "privileged (default, works everywhere)"
+      - value: sysbox
+        displayName: "sysbox (requires sysbox-runc on the Dokploy node)"
+      - value: rootless
+        displayName: "rootless (requires unprivileged user namespaces)"
+  DOKPLOY_WORKSPACE_IMAGE:
+    description: Workspace container image. Use an image from `dokploy-provider build-image` to skip package installation on start
+    default: "cruizba/ubuntu-dind:latest"
+  DOKPLOY_SIDECARS:
+    description: Extra compose services (e.g. Postgres, Redis) started next to the workspace, as a YAML snippet or the path to a YAML file
+    type: multiline
+  DOKPLOY_POST_SETUP_SCRIPT:
+    description: Script run as root in the workspace container after SSH is configured, inline or as the path to a script file. Its output goes to /var/log/devpod-post-setup.log
+    type: multiline
+  DOKPLOY_DOTFILES_URL:
+    description: Git URL of a dotfiles repository installed into the workspace container after SSH is configured
+  DOKPLOY_RETAIN_VOLUMES_ON_DELETE:
+    description: Keep the workspace and inner Docker volumes on delete so that recreating the machine reattaches them
+    default: "false"
+    type: boolean
+  DOKPLOY_START_TIMEOUT:
+    description: How long start waits for the workspace to accept SSH connections before failing
+    default: "5m"
+    type: duration
+  DOKPLOY_STOP_TIMEOUT:
+    description: How long stop waits for the workspace container to be down before failing
+    default: "2m"
+    type: duration
+  DOKPLOY_AUTO_UPGRADE:
+    description: Upgrade the workspace compose file to the provider's current template on start
+    default: "false"
+    type: boolean
+  DOKPLOY_INACTIVITY_TIMEOUT:
+    description: Stop the workspace after this long without SSH sessions (e.g. 2h). Empty disables auto-stop
+    type: duration
+  DOKPLOY_INACTIVITY_API_TOKEN:
+    description: API token the workspace uses to stop itself when idle. Use a token of a Dokploy user restricted to the workspace project
+    password: true
+  DOKPLOY_JOIN_DOKPLOY_NETWORK:
+    description: Attach the workspace to the shared dokploy-network (e.g. to expose it through Traefik domains). By default each workspace gets its own isolated network
+    default: "false"
+    type: boolean
+  DOKPLOY_ALLOWED_SERVICES:
+    description: Comma-separated Dokploy services the workspace may reach without joining dokploy-network, as name:port (e.g. my-db-abc123:5432)
+  DOKPLOY_DOCKER_REGISTRY_MIRRORS:
+    description: Comma-separated registry mirrors for the inner Docker daemon (e.g. https://mirror.gcr.io)
+  DOKPLOY_DOCKER_INSECURE_REGISTRIES:
+    description: Comma-separated registries the inner Docker daemon may reach over plain HTTP (e.g. registry.local:5000)
+  DOKPLOY_DOCKER_ADDRESS_POOL:
+    description: Default address pool for inner Docker networks as base-cidr:size, to avoid clashes with host or VPN ranges (e.g. 10.201.0.0/16:24)
+  DOKPLOY_DOCKER_MTU:
+    description: MTU of the inner Docker bridge network (e.g. 1400 behind overlays or VPNs)
+    type: number
+  DOKPLOY_DOCKER_LOG_MAX_SIZE:
+    description: Maximum size of a container log file in the inner Docker daemon (e.g. 10m)
+  DOKPLOY_DOCKER_LOG_MAX_FILE:
+    description: Number of rotated log files kept per container in the inner Docker daemon
+    type: number
+  DOKPLOY_DOCKER_STORAGE_DRIVER:
+    description: Storage driver of the inner Docker daemon (e.g. overlay2, fuse-overlayfs, vfs)
+  DOKPLOY_SHARED_CACHE:
+    description: Pull images through a shared cache registry on the Dokploy node so that new workspaces reuse images pulled by earlier ones
+    default: "false"
+    type: boolean
 
 binaries:
   DOKPLOY_PROVIDER_BINARY:
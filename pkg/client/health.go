@@ -0,0 +1,23 @@
+package client
+
+// Health represents the health of a workspace container as reported by its healthcheck
+type Health string
+
+const (
+	// HealthNone indicates the container has no healthcheck (e.g. created by an older provider version)
+	HealthNone Health = "none"
+
+	// HealthStarting indicates the setup script is still running (sshd or dockerd not up yet)
+	HealthStarting Health = "starting"
+
+	// HealthHealthy indicates sshd and dockerd are running inside the container
+	HealthHealthy Health = "healthy"
+
+	// HealthUnhealthy indicates sshd or dockerd stopped responding after setup
+	HealthUnhealthy Health = "unhealthy"
+)
+
+// String returns the string representation of the health
+func (h Health) String() string {
+	return string(h)
+}
@@ -0,0 +1,46 @@
+package client
+
+import "fmt"
+
+// Setup progress states written by the setup script to /run/devpod-progress.json
+const (
+	// SetupRunning indicates a setup stage is in progress
+	SetupRunning = "running"
+
+	// SetupFailed indicates the setup stopped with an error
+	SetupFailed = "failed"
+
+	// SetupReady indicates the setup (including post-setup hooks) has finished
+	SetupReady = "ready"
+)
+
+// SetupProgress is the machine-readable setup progress reported by the workspace container
+type SetupProgress struct {
+	Stage     int    `json:"stage"`
+	Total     int    `json:"total"`
+	Status    string `json:"status"`
+	Message   string `json:"message"`
+	Error     string `json:"error"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Failed reports whether the setup stopped with an error
+func (p *SetupProgress) Failed() bool {
+	return p.Status == SetupFailed
+}
+
+// String returns a short description such as "installing sshd and tools (2/4)"
+func (p *SetupProgress) String() string {
+	if p.Stage == 0 {
+		return p.Message
+	}
+	return fmt.Sprintf("%s (%d/%d)", p.Message, p.Stage, p.Total)
+}
+
+// Err returns the setup failure as an error, or nil when the setup has not failed
+func (p *SetupProgress) Err() error {
+	if !p.Failed() {
+		return nil
+	}
+	return fmt.Errorf("workspace setup failed while %s: %s", p.String(), p.Error)
+}
@@ -3,6 +3,7 @@ package dokploy
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -15,6 +16,9 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ErrNotFound is returned, wrapped, when the requested Dokploy resource does not exist
+var ErrNotFound = errors.New("not found")
+
 // Client represents a Dokploy API client
 type Client struct {
 	baseURL    string
@@ -121,12 +125,99 @@ type CreatePortRequest struct {
 
 // Compose represents a Dokploy Docker Compose service
 type Compose struct {
-	ComposeID   string `json:"composeId"`
+	ComposeID   string       `json:"composeId"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	ProjectID   string       `json:"projectId"`
+	Status      string       `json:"composeStatus"`
+	ComposeType string       `json:"composeType"`
+	SourceType  string       `json:"sourceType"`
+	AppName     string       `json:"appName"`
+	ServerID    string       `json:"serverId"`
+	ComposeFile string       `json:"composeFile"`
+	Env         string       `json:"env"`
+	CreatedAt   string       `json:"createdAt"`
+	Deployments []Deployment `json:"deployments"`
+}
+
+// Deployment represents a deployment of a Dokploy service
+type Deployment struct {
+	DeploymentID string `json:"deploymentId"`
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	LogPath      string `json:"logPath"`
+	CreatedAt    string `json:"createdAt"`
+}
+
+// Container represents a container of a Dokploy service
+type Container struct {
+	ContainerID string `json:"containerId"`
 	Name        string `json:"name"`
-	Description string `json:"description"`
-	ProjectID   string `json:"projectId"`
-	Status      string `json:"composeStatus"`
-	ComposeType string `json:"composeType"`
+	State       string `json:"state"`
+}
+
+// ContainerInspect represents the subset of `docker inspect` output used by the provider
+type ContainerInspect struct {
+	State struct {
+		Status string `json:"Status"`
+		Health *struct {
+			Status string `json:"Status"`
+			Log    []struct {
+				ExitCode int    `json:"ExitCode"`
+				Output   string `json:"Output"`
+			} `json:"Log"`
+		} `json:"Health"`
+	} `json:"State"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			NetworkID string `json:"NetworkID"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// Health returns the container health reported by its healthcheck
+func (i *ContainerInspect) Health() client.Health {
+	if i.State.Health == nil {
+		return client.HealthNone
+	}
+	switch i.State.Health.Status {
+	case "starting":
+		return client.HealthStarting
+	case "healthy":
+		return client.HealthHealthy
+	case "unhealthy":
+		return client.HealthUnhealthy
+	default:
+		return client.HealthNone
+	}
+}
+
+// LastHealthOutput returns the output of the most recent healthcheck run
+func (i *ContainerInspect) LastHealthOutput() string {
+	if i.State.Health == nil || len(i.State.Health.Log) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(i.State.Health.Log[len(i.State.Health.Log)-1].Output)
+}
+
+// SetupProgress returns the setup progress the healthcheck reports while the workspace is
+// not ready yet, or nil when the last healthcheck output holds no progress
+func (i *ContainerInspect) SetupProgress() *client.SetupProgress {
+	output := i.LastHealthOutput()
+	if !strings.HasPrefix(output, "{") {
+		return nil
+	}
+	var progress client.SetupProgress
+	if err := json.Unmarshal([]byte(output), &progress); err != nil {
+		return nil
+	}
+	switch progress.Status {
+	case client.SetupRunning, client.SetupFailed:
+		return &progress
+	default:
+		// A ready setup leaves the verdict to the healthcheck status
+		return nil
+	}
 }
 
 // CreateComposeRequest represents a Docker Compose creation request
@@ -135,12 +226,27 @@ type CreateComposeRequest struct {
 	Description string `json:"description"`
 	ProjectID   string `json:"projectId"`
 	ComposeType string `json:"composeType"` // "docker-compose" or "stack"
+	ServerID    string `json:"serverId,omitempty"` // remote server, empty for the Dokploy host
+}
+
+// RenameComposeRequest represents a request to rename a Docker Compose service
+type RenameComposeRequest struct {
+	ComposeID string `json:"composeId"`
+	Name      string `json:"name"`
+}
+
+// Server represents a remote server managed by Dokploy
+type Server struct {
+	ServerID  string `json:"serverId"`
+	Name      string `json:"name"`
+	IPAddress string `json:"ipAddress"`
 }
 
 // SaveComposeFileRequest represents a request to save docker-compose.yml content
 type SaveComposeFileRequest struct {
 	ComposeID     string `json:"composeId"`
 	DockerCompose string `json:"dockerCompose"`
+	Env           string `json:"env"`
 }
 
 // UpdateComposeRequest represents a request to update Docker Compose configuration
@@ -149,6 +255,7 @@ type UpdateComposeRequest struct {
 	ComposeFile  string `json:"composeFile"`
 	SourceType   string `json:"sourceType"`
 	ComposePath  string `json:"composePath"`
+	Env          string `json:"env"`
 }
 
 // DeployComposeRequest represents a Docker Compose deployment request
@@ -172,6 +279,21 @@ func (c *Client) HealthCheck() error {
 	return nil
 }
 
+// ServerTime returns the time reported in the Date header of the Dokploy server
+func (c *Client) ServerTime() (time.Time, error) {
+	resp, err := c.makeRequest("GET", "/api/settings.health", nil)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("server did not send a valid Date header: %w", err)
+	}
+	return serverTime, nil
+}
+
 // GetAllProjects retrieves all projects
 func (c *Client) GetAllProjects() ([]Project, error) {
 	resp, err := c.makeRequest("GET", "/api/project.all", nil)
@@ -188,6 +310,28 @@ func (c *Client) GetAllProjects() ([]Project, error) {
 	return projects, nil
 }
 
+// GetServer retrieves a remote server by ID
+func (c *Client) GetServer(serverID string) (*Server, error) {
+	endpoint := fmt.Sprintf("/api/server.one?serverId=%s", url.QueryEscape(serverID))
+	resp, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get server %s, status: %d, body: %s", serverID, resp.StatusCode, string(body))
+	}
+
+	var server Server
+	if err := json.NewDecoder(resp.Body).Decode(&server); err != nil {
+		return nil, fmt.Errorf("failed to decode server response: %w", err)
+	}
+
+	return &server, nil
+}
+
 // CreateProject creates a new project
 func (c *Client) CreateProject(req CreateProjectRequest) (*Project, error) {
 	resp, err := c.makeRequest("POST", "/api/project.create", req)
@@ -321,7 +465,7 @@ func (c *Client) GetApplication(applicationID string) (*Application, error) {
 		}
 		if err := json.Unmarshal(body, &errorResp); err == nil {
 			if errorResp.Code == "NOT_FOUND" {
-				return nil, fmt.Errorf("application not found: %s", errorResp.Message)
+				return nil, fmt.Errorf("application %w: %s", ErrNotFound, errorResp.Message)
 			}
 		}
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
@@ -424,7 +568,7 @@ func (c *Client) GetApplicationByName(applicationName string) (*Application, err
 		}
 	}
 
-	return nil, fmt.Errorf("application with name '%s' not found", applicationName)
+	return nil, fmt.Errorf("application with name '%s' %w", applicationName, ErrNotFound)
 }
 
 // DeleteApplicationByName deletes an application by name
@@ -473,6 +617,21 @@ func (c *Client) CreateCompose(req CreateComposeRequest) (*Compose, error) {
 	return &compose, nil
 }
 
+// RenameCompose changes the name of a Docker Compose service
+func (c *Client) RenameCompose(req RenameComposeRequest) error {
+	resp, err := c.makeRequest("POST", "/api/compose.update", req)
+	if err != nil {
+		return fmt.Errorf("failed to rename compose service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to rename compose service, status: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
 // SaveComposeFile saves the docker-compose.yml content using the update endpoint
 func (c *Client) SaveComposeFile(req SaveComposeFileRequest) error {
 	// Convert to UpdateComposeRequest format
@@ -481,6 +640,7 @@ func (c *Client) SaveComposeFile(req SaveComposeFileRequest) error {
 		ComposeFile:  req.DockerCompose,
 		SourceType:   "raw", // Use raw source type for direct compose content
 		ComposePath:  "./docker-compose.yml", // Default compose path
+		Env:          req.Env,
 	}
 
 	resp, err := c.makeRequest("POST", "/api/compose.update", updateReq)
@@ -536,7 +696,7 @@ func (c *Client) GetCompose(composeID string) (*Compose, error) {
 		}
 		if err := json.Unmarshal(body, &errorResp); err == nil {
 			if errorResp.Code == "NOT_FOUND" {
-				return nil, fmt.Errorf("compose service not found: %s", errorResp.Message)
+				return nil, fmt.Errorf("compose service %w: %s", ErrNotFound, errorResp.Message)
 			}
 		}
 		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
@@ -550,11 +710,11 @@ func (c *Client) GetCompose(composeID string) (*Compose, error) {
 	return &compose, nil
 }
 
-// DeleteCompose deletes a Docker Compose service
-func (c *Client) DeleteCompose(composeID string) error {
+// DeleteCompose deletes a Docker Compose service, optionally removing its volumes
+func (c *Client) DeleteCompose(composeID string, deleteVolumes bool) error {
 	req := map[string]interface{}{
 		"composeId":     composeID,
-		"deleteVolumes": true, // Delete associated volumes for complete cleanup
+		"deleteVolumes": deleteVolumes,
 	}
 	resp, err := c.makeRequest("POST", "/api/compose.delete", req)
 	if err != nil {
@@ -563,7 +723,14 @@ func (c *Client) DeleteCompose(composeID string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to delete compose service, status: %d", resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		var errorResp struct {
+			Code string `json:"code"`
+		}
+		if resp.StatusCode == http.StatusNotFound || (json.Unmarshal(body, &errorResp) == nil && errorResp.Code == "NOT_FOUND") {
+			return fmt.Errorf("compose service %s %w", composeID, ErrNotFound)
+		}
+		return fmt.Errorf("failed to delete compose service, status: %d, body: %s", resp.StatusCode, string(body))
 	}
 
 	return nil
@@ -610,18 +777,27 @@ func (c *Client) GetComposeStatus(composeName string) (client.Status, error) {
 
 	c.logger.Debugf("Dokploy compose status for %s: '%s'", composeName, compose.Status)
 
-	switch compose.Status {
+	status, known := MapComposeStatus(compose.Status)
+	if !known {
+		c.logger.Warnf("Unknown Dokploy status '%s' for compose %s, treating as busy", compose.Status, composeName)
+	}
+	return status, nil
+}
+
+// MapComposeStatus maps a Dokploy compose status to the DevPod status. Unknown statuses
+// are reported as busy, with known set to false.
+func MapComposeStatus(composeStatus string) (status client.Status, known bool) {
+	switch composeStatus {
 	case "done", "running":
-		return client.StatusRunning, nil
+		return client.StatusRunning, true
 	case "idle", "stopped":
-		return client.StatusStopped, nil
+		return client.StatusStopped, true
 	case "error", "failed":
-		return client.StatusNotFound, nil
+		return client.StatusNotFound, true
 	case "building", "deploying", "restarting":
-		return client.StatusBusy, nil
+		return client.StatusBusy, true
 	default:
-		c.logger.Warnf("Unknown Dokploy status '%s' for compose %s, treating as busy", compose.Status, composeName)
-		return client.StatusBusy, nil
+		return client.StatusBusy, false
 	}
 }
 
@@ -642,17 +818,58 @@ func (c *Client) GetComposeByName(composeName string) (*Compose, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("compose service with name '%s' not found", composeName)
+	return nil, fmt.Errorf("compose service with name '%s' %w", composeName, ErrNotFound)
+}
+
+// FindComposesInProject returns the Docker Compose services with the given name in a project
+func (c *Client) FindComposesInProject(projectID, composeName string) ([]Compose, error) {
+	projects, err := c.GetAllProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	var composes []Compose
+	for _, project := range projects {
+		if project.ProjectID != projectID {
+			continue
+		}
+		for _, compose := range project.Composes {
+			if compose.Name == composeName {
+				composes = append(composes, compose)
+			}
+		}
+	}
+
+	return composes, nil
+}
+
+// FindComposes returns the Docker Compose services with the given name in every project
+func (c *Client) FindComposes(composeName string) ([]Compose, error) {
+	projects, err := c.GetAllProjects()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+
+	var composes []Compose
+	for _, project := range projects {
+		for _, compose := range project.Composes {
+			if compose.Name == composeName {
+				composes = append(composes, compose)
+			}
+		}
+	}
+
+	return composes, nil
 }
 
 // DeleteComposeByName deletes a Docker Compose service by name
-func (c *Client) DeleteComposeByName(composeName string) error {
+func (c *Client) DeleteComposeByName(composeName string, deleteVolumes bool) error {
 	compose, err := c.GetComposeByName(composeName)
 	if err != nil {
 		return fmt.Errorf("failed to find compose service: %w", err)
 	}
 	
-	return c.DeleteCompose(compose.ComposeID)
+	return c.DeleteCompose(compose.ComposeID, deleteVolumes)
 }
 
 // StartComposeByName starts a Docker Compose service by name
@@ -675,6 +892,108 @@ func (c *Client) StopComposeByName(composeName string) error {
 	return c.StopCompose(compose.ComposeID)
 }
 
+// GetComposeContainers retrieves the containers belonging to a Docker Compose service
+func (c *Client) GetComposeContainers(compose *Compose) ([]Container, error) {
+	query := url.Values{}
+	query.Set("appName", compose.AppName)
+	query.Set("appType", "docker-compose")
+	if compose.ServerID != "" {
+		query.Set("serverId", compose.ServerID)
+	}
+
+	resp, err := c.makeRequest("GET", "/api/docker.getContainersByAppNameMatch?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get compose containers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get compose containers, status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var containers []Container
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode containers response: %w", err)
+	}
+
+	return containers, nil
+}
+
+// GetContainers retrieves all containers of a server, or of the Dokploy host when serverID is empty
+func (c *Client) GetContainers(serverID string) ([]Container, error) {
+	endpoint := "/api/docker.getContainers"
+	if serverID != "" {
+		endpoint += "?" + url.Values{"serverId": {serverID}}.Encode()
+	}
+
+	resp, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get containers: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get containers, status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var containers []Container
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to decode containers response: %w", err)
+	}
+
+	return containers, nil
+}
+
+// GetWorkspaceContainer retrieves the devpod-workspace container of a Docker Compose service
+func (c *Client) GetWorkspaceContainer(compose *Compose) (*Container, error) {
+	return c.GetServiceContainer(compose, "devpod-workspace")
+}
+
+// GetServiceContainer retrieves the container of a service of a Docker Compose service
+func (c *Client) GetServiceContainer(compose *Compose, service string) (*Container, error) {
+	containers, err := c.GetComposeContainers(compose)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, container := range containers {
+		if strings.Contains(container.Name, service) {
+			return &container, nil
+		}
+	}
+
+	return nil, fmt.Errorf("workspace container %w for compose service '%s'", ErrNotFound, compose.Name)
+}
+
+// InspectContainer retrieves the `docker inspect` output of a container
+func (c *Client) InspectContainer(containerID, serverID string) (*ContainerInspect, error) {
+	query := url.Values{}
+	query.Set("containerId", containerID)
+	if serverID != "" {
+		query.Set("serverId", serverID)
+	}
+
+	resp, err := c.makeRequest("GET", "/api/docker.getConfig?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to inspect container, status: %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var inspect ContainerInspect
+	if err := json.NewDecoder(resp.Body).Decode(&inspect); err != nil {
+		return nil, fmt.Errorf("failed to decode container inspect response: %w", err)
+	}
+
+	return &inspect, nil
+}
+
 // makeRequest makes an HTTP request to the Dokploy API with comprehensive debug logging
 func (c *Client) makeRequest(method, endpoint string, body interface{}) (*http.Response, error) {
 	var reqBody io.Reader
@@ -0,0 +1,205 @@
+package dokploy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/client"
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/sirupsen/logrus"
+)
+
+// inspectWithHealth returns a container inspect of a running container with one healthcheck run
+func inspectWithHealth(t *testing.T, status, output string) *ContainerInspect {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{
+		"State": map[string]interface{}{
+			"Status": "running",
+			"Health": map[string]interface{}{
+				"Status": status,
+				"Log":    []map[string]interface{}{{"ExitCode": 1, "Output": output}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var inspect ContainerInspect
+	if err := json.Unmarshal(data, &inspect); err != nil {
+		t.Fatal(err)
+	}
+	return &inspect
+}
+
+func TestContainerInspectSetupProgress(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantStatus string
+		wantString string
+	}{
+		{
+			name:       "running stage",
+			output:     `{"stage":2,"total":4,"status":"running","message":"installing sshd and tools","error":"","timestamp":"2026-10-18T10:00:00Z"}` + "\n",
+			wantStatus: client.SetupRunning,
+			wantString: "installing sshd and tools (2/4)",
+		},
+		{
+			name:       "failed stage",
+			output:     `{"stage":1,"total":4,"status":"failed","message":"starting Docker daemon","error":"Docker daemon failed to start"}`,
+			wantStatus: client.SetupFailed,
+			wantString: "starting Docker daemon (1/4)",
+		},
+		{name: "ready", output: `{"stage":4,"total":4,"status":"ready","message":"ready"}`},
+		{name: "unknown status", output: `{"stage":1,"total":4,"status":"paused"}`},
+		{name: "plain output", output: "sshd not running"},
+		{name: "invalid JSON", output: `{"stage":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := inspectWithHealth(t, "starting", tt.output).SetupProgress()
+			if tt.wantStatus == "" {
+				if progress != nil {
+					t.Errorf("SetupProgress() = %+v, want nil", progress)
+				}
+				return
+			}
+			if progress == nil {
+				t.Fatal("SetupProgress() = nil")
+			}
+			if progress.Status != tt.wantStatus || progress.String() != tt.wantString {
+				t.Errorf("SetupProgress() = %s [%s], want %s [%s]", progress, progress.Status, tt.wantString, tt.wantStatus)
+			}
+			if (progress.Err() != nil) != (tt.wantStatus == client.SetupFailed) {
+				t.Errorf("Err() = %v for status %s", progress.Err(), progress.Status)
+			}
+		})
+	}
+}
+
+func TestContainerInspectHealth(t *testing.T) {
+	tests := []struct {
+		status string
+		want   client.Health
+	}{
+		{status: "starting", want: client.HealthStarting},
+		{status: "healthy", want: client.HealthHealthy},
+		{status: "unhealthy", want: client.HealthUnhealthy},
+		{status: "", want: client.HealthNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			if got := inspectWithHealth(t, tt.status, "").Health(); got != tt.want {
+				t.Errorf("Health() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&ContainerInspect{}).Health(); got != client.HealthNone {
+		t.Errorf("Health() without healthcheck = %s, want %s", got, client.HealthNone)
+	}
+}
+
+func TestMapComposeStatus(t *testing.T) {
+	tests := []struct {
+		status    string
+		want      client.Status
+		wantKnown bool
+	}{
+		{status: "done", want: client.StatusRunning, wantKnown: true},
+		{status: "running", want: client.StatusRunning, wantKnown: true},
+		{status: "idle", want: client.StatusStopped, wantKnown: true},
+		{status: "error", want: client.StatusNotFound, wantKnown: true},
+		{status: "deploying", want: client.StatusBusy, wantKnown: true},
+		{status: "something-new", want: client.StatusBusy, wantKnown: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			got, known := MapComposeStatus(tt.status)
+			if got != tt.want || known != tt.wantKnown {
+				t.Errorf("MapComposeStatus(%q) = %s, %v, want %s, %v", tt.status, got, known, tt.want, tt.wantKnown)
+			}
+		})
+	}
+}
+
+func TestNotFoundErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/project.all":
+			fmt.Fprint(w, `[{"projectId":"p1","name":"devpod-workspaces","compose":[{"composeId":"c1","name":"ws"}]}]`)
+		case "/api/compose.one":
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"Compose not found","code":"NOT_FOUND"}`)
+		case "/api/docker.getContainersByAppNameMatch":
+			fmt.Fprint(w, `[]`)
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Unauthorized","code":"UNAUTHORIZED"}`)
+		}
+	}))
+	defer server.Close()
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	dokployClient := NewClient(&options.Options{DokployServerURL: server.URL, DokployAPIToken: "token"}, logger)
+
+	if _, err := dokployClient.GetCompose("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetCompose() error = %v, want ErrNotFound", err)
+	}
+	if _, err := dokployClient.GetComposeByName("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetComposeByName() error = %v, want ErrNotFound", err)
+	}
+	if _, err := dokployClient.GetComposeByName("ws"); err != nil {
+		t.Errorf("GetComposeByName() error = %v", err)
+	}
+	if _, err := dokployClient.GetWorkspaceContainer(&Compose{Name: "ws", AppName: "ws-abc"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetWorkspaceContainer() error = %v, want ErrNotFound", err)
+	}
+	if _, err := dokployClient.GetServer("s1"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("GetServer() error = %v, want an API error other than ErrNotFound", err)
+	}
+}
+
+func TestDeleteComposeErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{name: "deleted", status: http.StatusOK, body: `true`},
+		{name: "not found", status: http.StatusNotFound, body: `{"message":"Compose not found","code":"NOT_FOUND"}`, wantErr: true, wantNotFound: true},
+		{name: "not found code", status: http.StatusBadRequest, body: `{"code":"NOT_FOUND"}`, wantErr: true, wantNotFound: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, body: `{"code":"UNAUTHORIZED"}`, wantErr: true},
+		{name: "server error", status: http.StatusInternalServerError, body: `oops`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			logger := logrus.New()
+			logger.SetOutput(io.Discard)
+			dokployClient := NewClient(&options.Options{DokployServerURL: server.URL, DokployAPIToken: "token"}, logger)
+
+			err := dokployClient.DeleteCompose("c1", true)
+			if (err != nil) != tt.wantErr || errors.Is(err, ErrNotFound) != tt.wantNotFound {
+				t.Errorf("DeleteCompose() error = %v, wantErr %v, wantNotFound %v", err, tt.wantErr, tt.wantNotFound)
+			}
+		})
+	}
+}
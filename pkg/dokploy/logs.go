@@ -0,0 +1,91 @@
+package dokploy
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// logIdleTimeout ends a non-following log stream once no more lines arrive. The Dokploy log
+// endpoints always follow, so this is how a one-shot read detects the end of the log.
+const logIdleTimeout = 5 * time.Second
+
+// remoteLogIdleTimeout replaces logIdleTimeout for remote servers, whose logs Dokploy relays
+// over SSH and which can pause for several seconds in the middle of a stream
+const remoteLogIdleTimeout = 20 * time.Second
+
+// ContainerLogsOptions selects which container log lines are streamed
+type ContainerLogsOptions struct {
+	Tail        int    // number of lines from the end, 0 for all
+	Since       string // docker --since value (e.g. 10m or an RFC 3339 timestamp), empty for all
+	Follow      bool
+	IdleTimeout time.Duration // end of the log when not following, 0 for the default
+}
+
+// LatestDeployment returns the most recent deployment of a compose service, or nil when it was never deployed
+func (c *Compose) LatestDeployment() *Deployment {
+	var latest *Deployment
+	for i := range c.Deployments {
+		// Dokploy timestamps are ISO 8601 and compare lexically
+		if latest == nil || c.Deployments[i].CreatedAt > latest.CreatedAt {
+			latest = &c.Deployments[i]
+		}
+	}
+	return latest
+}
+
+// StreamDeploymentLogs copies the log file of a deployment to out. idleTimeout is the end of
+// the log when not following, 0 for the default.
+func (c *Client) StreamDeploymentLogs(deployment *Deployment, serverID string, follow bool, idleTimeout time.Duration, out io.Writer) error {
+	if deployment.LogPath == "" {
+		return fmt.Errorf("deployment %s has no log file", deployment.DeploymentID)
+	}
+
+	query := url.Values{}
+	query.Set("logPath", deployment.LogPath)
+	if serverID != "" {
+		query.Set("serverId", serverID)
+	}
+
+	return c.streamWebSocket("/listen-deployment", query, out, idleTimeoutFor(follow, serverID, idleTimeout))
+}
+
+// StreamContainerLogs copies the logs of a container to out
+func (c *Client) StreamContainerLogs(containerID, serverID string, opts ContainerLogsOptions, out io.Writer) error {
+	tail := "all"
+	if opts.Tail > 0 {
+		tail = strconv.Itoa(opts.Tail)
+	}
+	since := opts.Since
+	if since == "" {
+		since = "all"
+	}
+
+	query := url.Values{}
+	query.Set("containerId", containerID)
+	query.Set("tail", tail)
+	query.Set("since", since)
+	query.Set("search", "")
+	query.Set("runType", "native")
+	if serverID != "" {
+		query.Set("serverId", serverID)
+	}
+
+	return c.streamWebSocket("/docker-container-logs", query, out, idleTimeoutFor(opts.Follow, serverID, opts.IdleTimeout))
+}
+
+// idleTimeoutFor returns the idle timeout of a log stream, 0 when following
+func idleTimeoutFor(follow bool, serverID string, override time.Duration) time.Duration {
+	switch {
+	case follow:
+		return 0
+	case override > 0:
+		return override
+	case serverID != "":
+		return remoteLogIdleTimeout
+	default:
+		return logIdleTimeout
+	}
+}
@@ -0,0 +1,103 @@
+package dokploy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsFirstMessageTimeout bounds the wait for the first message when not following; Dokploy
+// may have to reach a remote server over SSH before the first log line arrives
+const wsFirstMessageTimeout = 15 * time.Second
+
+// wsMaxMessageSize bounds a single log stream message
+const wsMaxMessageSize = 64 << 20
+
+// streamWebSocket connects to a Dokploy WebSocket endpoint and copies every message to out.
+// With an idle timeout of 0 it follows the stream until the server closes it; otherwise it
+// returns once no message arrived for that long.
+func (c *Client) streamWebSocket(path string, query url.Values, out io.Writer, idleTimeout time.Duration) error {
+	conn, err := c.dialWebSocket(path, query)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	timeout := wsFirstMessageTimeout
+	for {
+		if idleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		messageType, payload, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				closeWebSocket(conn)
+				return nil
+			}
+			// Dokploy ends finished streams by closing the connection, with or without a close frame
+			if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway,
+				websocket.CloseNoStatusReceived, websocket.CloseAbnormalClosure) {
+				return nil
+			}
+			return fmt.Errorf("failed to read log stream: %w", err)
+		}
+
+		if messageType == websocket.TextMessage || messageType == websocket.BinaryMessage {
+			if _, err := out.Write(payload); err != nil {
+				return err
+			}
+			timeout = idleTimeout
+		}
+	}
+}
+
+// dialWebSocket opens a WebSocket connection to the Dokploy server, authenticated with the API token
+func (c *Client) dialWebSocket(path string, query url.Values) (*websocket.Conn, error) {
+	endpoint, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server URL: %w", err)
+	}
+	switch endpoint.Scheme {
+	case "https":
+		endpoint.Scheme = "wss"
+	default:
+		endpoint.Scheme = "ws"
+	}
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	header := http.Header{}
+	header.Set("x-api-key", c.apiToken)
+
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+	}
+
+	c.logger.Debugf("Opening WebSocket %s", endpoint.Path)
+	conn, resp, err := dialer.Dial(endpoint.String(), header)
+	if err != nil {
+		if errors.Is(err, websocket.ErrBadHandshake) && resp != nil {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+			resp.Body.Close()
+			return nil, fmt.Errorf("WebSocket handshake failed, status: %d, body: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("failed to connect to %s: %w", endpoint.Host, err)
+	}
+	return conn, nil
+}
+
+// closeWebSocket tells the server that the client stops reading
+func closeWebSocket(conn *websocket.Conn) {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(10*time.Second))
+}
@@ -0,0 +1,119 @@
+package dokploy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NaNomicon/dokploy-devpod-provider/pkg/options"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// newWebSocketTestClient returns a client for a server that sends the given messages on
+// /logs, then closes the stream when closeAfter is set or keeps it open otherwise
+func newWebSocketTestClient(t *testing.T, messages []string, closeAfter bool) *Client {
+	t.Helper()
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, "Unauthorized")
+			return
+		}
+		if r.URL.Path != "/logs" || r.URL.Query().Get("containerId") != "c1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for _, message := range messages {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+				return
+			}
+		}
+		if closeAfter {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		}
+		// Wait for the client to close the connection
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return NewClient(&options.Options{DokployServerURL: server.URL, DokployAPIToken: "token"}, logger)
+}
+
+func TestStreamWebSocket(t *testing.T) {
+	query := map[string][]string{"containerId": {"c1"}}
+	large := strings.Repeat("x", 70000) + "\n"
+
+	tests := []struct {
+		name        string
+		messages    []string
+		closeAfter  bool
+		idleTimeout time.Duration
+		want        string
+	}{
+		{name: "server closes the stream", messages: []string{"line 1\n", "line 2\n"}, closeAfter: true, want: "line 1\nline 2\n"},
+		{name: "idle timeout ends the stream", messages: []string{"line 1\n"}, idleTimeout: 100 * time.Millisecond, want: "line 1\n"},
+		{name: "large message", messages: []string{large}, closeAfter: true, want: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dokployClient := newWebSocketTestClient(t, tt.messages, tt.closeAfter)
+			var out strings.Builder
+			if err := dokployClient.streamWebSocket("/logs", query, &out, tt.idleTimeout); err != nil {
+				t.Fatalf("streamWebSocket() error = %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("streamWebSocket() wrote %d bytes, want %d", out.Len(), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestStreamWebSocketHandshakeError(t *testing.T) {
+	dokployClient := newWebSocketTestClient(t, nil, true)
+	dokployClient.apiToken = "wrong"
+
+	err := dokployClient.streamWebSocket("/logs", map[string][]string{"containerId": {"c1"}}, io.Discard, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "status: 401") {
+		t.Errorf("streamWebSocket() error = %v, want the handshake status", err)
+	}
+}
+
+func TestIdleTimeoutFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		follow   bool
+		serverID string
+		override time.Duration
+		want     time.Duration
+	}{
+		{name: "follow", follow: true, override: time.Minute, want: 0},
+		{name: "local server", want: logIdleTimeout},
+		{name: "remote server", serverID: "s1", want: remoteLogIdleTimeout},
+		{name: "override", serverID: "s1", override: time.Minute, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idleTimeoutFor(tt.follow, tt.serverID, tt.override); got != tt.want {
+				t.Errorf("idleTimeoutFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package options
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// MachineTypeSpec describes the resource limits applied to a workspace container
+type MachineTypeSpec struct {
+	CPUs       string `json:"cpus"`
+	Memory     string `json:"memory"`
+	MemorySwap string `json:"memorySwap,omitempty"`
+	PidsLimit  int    `json:"pidsLimit,omitempty"`
+	ShmSize    string `json:"shmSize,omitempty"`
+}
+
+// Defaults used when a user-defined machine type leaves a limit unset
+const (
+	defaultPidsLimit = 4096
+	defaultShmSize   = "64m"
+)
+
+// builtinMachineTypes is the catalogue shipped with the provider
+var builtinMachineTypes = map[string]MachineTypeSpec{
+	"small": {
+		CPUs:       "2",
+		Memory:     "4g",
+		MemorySwap: "4g",
+		PidsLimit:  4096,
+		ShmSize:    "1g",
+	},
+	"medium": {
+		CPUs:       "4",
+		Memory:     "8g",
+		MemorySwap: "8g",
+		PidsLimit:  8192,
+		ShmSize:    "2g",
+	},
+	"large": {
+		CPUs:       "8",
+		Memory:     "16g",
+		MemorySwap: "16g",
+		PidsLimit:  16384,
+		ShmSize:    "4g",
+	},
+}
+
+// MachineTypes returns the built-in catalogue merged with any user-defined types.
+// User-defined types are read from DOKPLOY_MACHINE_TYPES_FILE and DOKPLOY_MACHINE_TYPES
+// (inline JSON), in that order, and may override the built-in names.
+func (o *Options) MachineTypes() (map[string]MachineTypeSpec, error) {
+	catalogue := make(map[string]MachineTypeSpec, len(builtinMachineTypes))
+	for name, spec := range builtinMachineTypes {
+		catalogue[name] = spec
+	}
+
+	if o.MachineTypesFile != "" {
+		data, err := os.ReadFile(o.MachineTypesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read machine types file %s: %w", o.MachineTypesFile, err)
+		}
+		if err := mergeMachineTypes(catalogue, data); err != nil {
+			return nil, fmt.Errorf("invalid machine types file %s: %w", o.MachineTypesFile, err)
+		}
+	}
+
+	if o.MachineTypesJSON != "" {
+		if err := mergeMachineTypes(catalogue, []byte(o.MachineTypesJSON)); err != nil {
+			return nil, fmt.Errorf("invalid DOKPLOY_MACHINE_TYPES: %w", err)
+		}
+	}
+
+	return catalogue, nil
+}
+
+// ResolveMachineType returns the resource limits for the configured machine type
+func (o *Options) ResolveMachineType() (MachineTypeSpec, error) {
+	catalogue, err := o.MachineTypes()
+	if err != nil {
+		return MachineTypeSpec{}, err
+	}
+
+	spec, ok := catalogue[o.MachineType]
+	if !ok {
+		names := make([]string, 0, len(catalogue))
+		for name := range catalogue {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return MachineTypeSpec{}, fmt.Errorf("unknown machine type '%s' (available: %s)", o.MachineType, strings.Join(names, ", "))
+	}
+
+	return spec, nil
+}
+
+// mergeMachineTypes parses a JSON mapping of machine types and adds it to the catalogue
+func mergeMachineTypes(catalogue map[string]MachineTypeSpec, data []byte) error {
+	var custom map[string]MachineTypeSpec
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return fmt.Errorf("failed to parse machine types: %w", err)
+	}
+
+	for name, spec := range custom {
+		if spec.CPUs == "" || spec.Memory == "" {
+			return fmt.Errorf("machine type '%s' must set both cpus and memory", name)
+		}
+		if spec.MemorySwap == "" {
+			// Same as memory: no swap beyond the memory limit
+			spec.MemorySwap = spec.Memory
+		}
+		if spec.PidsLimit == 0 {
+			spec.PidsLimit = defaultPidsLimit
+		}
+		if spec.ShmSize == "" {
+			spec.ShmSize = defaultShmSize
+		}
+		catalogue[name] = spec
+	}
+
+	return nil
+}
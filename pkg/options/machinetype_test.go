@@ -0,0 +1,107 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeMachineTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]MachineTypeSpec
+		wantErr bool
+	}{
+		{
+			name: "new type gets defaults",
+			data: `{"xlarge": {"cpus": "16", "memory": "32g"}}`,
+			want: map[string]MachineTypeSpec{
+				"xlarge": {CPUs: "16", Memory: "32g", MemorySwap: "32g", PidsLimit: defaultPidsLimit, ShmSize: defaultShmSize},
+			},
+		},
+		{
+			name: "override keeps explicit limits",
+			data: `{"small": {"cpus": "1", "memory": "2g", "memorySwap": "3g", "pidsLimit": 100, "shmSize": "128m"}}`,
+			want: map[string]MachineTypeSpec{
+				"small": {CPUs: "1", Memory: "2g", MemorySwap: "3g", PidsLimit: 100, ShmSize: "128m"},
+			},
+		},
+		{
+			name:    "missing memory",
+			data:    `{"tiny": {"cpus": "1"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			data:    `{"tiny":`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogue := map[string]MachineTypeSpec{}
+			err := mergeMachineTypes(catalogue, []byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mergeMachineTypes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for name, want := range tt.want {
+				if got := catalogue[name]; got != want {
+					t.Errorf("catalogue[%q] = %+v, want %+v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveMachineType(t *testing.T) {
+	typesFile := filepath.Join(t.TempDir(), "types.json")
+	if err := os.WriteFile(typesFile, []byte(`{"gpu": {"cpus": "12", "memory": "48g"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantCPU string
+		wantErr bool
+	}{
+		{name: "built-in", opts: Options{MachineType: "medium"}, wantCPU: "4"},
+		{name: "from file", opts: Options{MachineType: "gpu", MachineTypesFile: typesFile}, wantCPU: "12"},
+		{name: "inline wins over file", opts: Options{MachineType: "gpu", MachineTypesFile: typesFile, MachineTypesJSON: `{"gpu": {"cpus": "6", "memory": "24g"}}`}, wantCPU: "6"},
+		{name: "unknown", opts: Options{MachineType: "huge"}, wantErr: true},
+		{name: "missing file", opts: Options{MachineType: "small", MachineTypesFile: typesFile + ".missing"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := tt.opts.ResolveMachineType()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveMachineType() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && spec.CPUs != tt.wantCPU {
+				t.Errorf("CPUs = %q, want %q", spec.CPUs, tt.wantCPU)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvMachineType(t *testing.T) {
+	t.Setenv("DOKPLOY_SERVER_URL", "https://dokploy.example.com")
+	t.Setenv("DOKPLOY_API_TOKEN", "token")
+	t.Setenv("DOKPLOY_MACHINE_TYPES", `{"xlarge": {"cpus": "16", "memory": "32g"}}`)
+
+	t.Setenv("MACHINE_TYPE", "xlarge")
+	if _, err := LoadFromEnv(); err != nil {
+		t.Errorf("custom machine type rejected: %v", err)
+	}
+
+	t.Setenv("MACHINE_TYPE", "huge")
+	if _, err := LoadFromEnv(); err == nil {
+		t.Error("unknown machine type accepted")
+	}
+}
@@ -3,6 +3,24 @@ package options
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWorkspaceImage is the Docker-in-Docker base image used for workspaces
+const DefaultWorkspaceImage = "cruizba/ubuntu-dind:latest"
+
+// Isolation modes for the workspace container
+const (
+	// IsolationPrivileged runs the workspace as a privileged container
+	IsolationPrivileged = "privileged"
+
+	// IsolationSysbox runs the workspace unprivileged under the sysbox-runc runtime
+	IsolationSysbox = "sysbox"
+
+	// IsolationRootless runs the workspace unprivileged with a rootless inner dockerd
+	IsolationRootless = "rootless"
 )
 
 // Options represents the configuration options for the Dokploy provider
@@ -15,6 +33,47 @@ type Options struct {
 	DokployProjectName string `json:"dokployProjectName"`
 	DokployServerID    string `json:"dokployServerID"`
 	MachineType        string `json:"machineType"`
+	MachineTypesFile   string `json:"machineTypesFile"`
+	MachineTypesJSON   string `json:"machineTypes"`
+
+	// Image used for the workspace container
+	WorkspaceImage string `json:"workspaceImage"`
+
+	// Isolation mode for Docker-in-Docker: privileged, sysbox or rootless
+	Isolation string `json:"isolation"`
+
+	// Extra compose services (YAML snippet or path to a YAML file)
+	Sidecars string `json:"sidecars"`
+
+	// User hooks run after the workspace setup (script or path to a script, dotfiles repository)
+	PostSetupScriptValue string `json:"postSetupScript"`
+	DotfilesURL          string `json:"dotfilesURL"`
+
+	// Networking options
+	JoinDokployNetwork bool     `json:"joinDokployNetwork"`
+	AllowedServices    []string `json:"allowedServices"`
+
+	// Inner Docker daemon options, rendered into /etc/docker/daemon.json
+	DockerRegistryMirrors    []string `json:"dockerRegistryMirrors"`
+	DockerInsecureRegistries []string `json:"dockerInsecureRegistries"`
+	DockerAddressPool        string   `json:"dockerAddressPool"`
+	DockerMTU                int      `json:"dockerMTU"`
+	DockerLogMaxSize         string   `json:"dockerLogMaxSize"`
+	DockerLogMaxFile         int      `json:"dockerLogMaxFile"`
+	DockerStorageDriver      string   `json:"dockerStorageDriver"`
+
+	// Shared node-level image cache used by all workspaces
+	SharedCache bool `json:"sharedCache"`
+
+	// Lifecycle options
+	RetainVolumesOnDelete bool          `json:"retainVolumesOnDelete"`
+	StartTimeout          time.Duration `json:"startTimeout"`
+	StopTimeout           time.Duration `json:"stopTimeout"`
+	AutoUpgrade           bool          `json:"autoUpgrade"`
+
+	// Inactivity auto-stop: idle time before the workspace stops itself, and the token it uses
+	InactivityTimeout  time.Duration `json:"inactivityTimeout"`
+	InactivityAPIToken string        `json:"inactivityAPIToken"`
 
 	// Machine identification
 	MachineID string `json:"machineID"`
@@ -22,13 +81,83 @@ type Options struct {
 
 // LoadFromEnv loads options from environment variables
 func LoadFromEnv() (*Options, error) {
+	return Load(os.Getenv)
+}
+
+// Load loads options from the variables returned by getenv, which returns "" for unset ones
+func Load(getenv func(key string) string) (*Options, error) {
 	opts := &Options{
-		DokployServerURL:   os.Getenv("DOKPLOY_SERVER_URL"),
-		DokployAPIToken:    os.Getenv("DOKPLOY_API_TOKEN"),
-		DokployProjectName: getEnvWithDefault("DOKPLOY_PROJECT_NAME", "devpod-workspaces"),
-		DokployServerID:    os.Getenv("DOKPLOY_SERVER_ID"),
-		MachineType:        getEnvWithDefault("MACHINE_TYPE", "small"),
-		MachineID:          os.Getenv("MACHINE_ID"),
+		DokployServerURL:   getenv("DOKPLOY_SERVER_URL"),
+		DokployAPIToken:    getenv("DOKPLOY_API_TOKEN"),
+		DokployProjectName: getEnvWithDefault(getenv, "DOKPLOY_PROJECT_NAME", "devpod-workspaces"),
+		DokployServerID:    getenv("DOKPLOY_SERVER_ID"),
+		MachineType:        getEnvWithDefault(getenv, "MACHINE_TYPE", "small"),
+		MachineTypesFile:   getenv("DOKPLOY_MACHINE_TYPES_FILE"),
+		MachineTypesJSON:   getenv("DOKPLOY_MACHINE_TYPES"),
+		Isolation:          getEnvWithDefault(getenv, "DOKPLOY_ISOLATION", IsolationPrivileged),
+		WorkspaceImage:     getEnvWithDefault(getenv, "DOKPLOY_WORKSPACE_IMAGE", DefaultWorkspaceImage),
+		Sidecars:           getenv("DOKPLOY_SIDECARS"),
+
+		PostSetupScriptValue: getenv("DOKPLOY_POST_SETUP_SCRIPT"),
+		DotfilesURL:          getenv("DOKPLOY_DOTFILES_URL"),
+
+		DockerRegistryMirrors:    getEnvList(getenv, "DOKPLOY_DOCKER_REGISTRY_MIRRORS"),
+		DockerInsecureRegistries: getEnvList(getenv, "DOKPLOY_DOCKER_INSECURE_REGISTRIES"),
+		DockerAddressPool:        getenv("DOKPLOY_DOCKER_ADDRESS_POOL"),
+		DockerLogMaxSize:         getenv("DOKPLOY_DOCKER_LOG_MAX_SIZE"),
+		DockerStorageDriver:      getenv("DOKPLOY_DOCKER_STORAGE_DRIVER"),
+
+		MachineID: getenv("MACHINE_ID"),
+	}
+
+	retainVolumes, err := getEnvBool(getenv, "DOKPLOY_RETAIN_VOLUMES_ON_DELETE", false)
+	if err != nil {
+		return nil, err
+	}
+	opts.RetainVolumesOnDelete = retainVolumes
+
+	if opts.StartTimeout, err = getEnvPositiveDuration(getenv, "DOKPLOY_START_TIMEOUT", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if opts.StopTimeout, err = getEnvPositiveDuration(getenv, "DOKPLOY_STOP_TIMEOUT", 2*time.Minute); err != nil {
+		return nil, err
+	}
+	if opts.AutoUpgrade, err = getEnvBool(getenv, "DOKPLOY_AUTO_UPGRADE", false); err != nil {
+		return nil, err
+	}
+	if opts.InactivityTimeout, err = getEnvDuration(getenv, "DOKPLOY_INACTIVITY_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+	opts.InactivityAPIToken = getenv("DOKPLOY_INACTIVITY_API_TOKEN")
+
+	switch opts.Isolation {
+	case IsolationPrivileged, IsolationSysbox, IsolationRootless:
+	default:
+		return nil, fmt.Errorf("DOKPLOY_ISOLATION must be one of %s, %s or %s, got '%s'",
+			IsolationPrivileged, IsolationSysbox, IsolationRootless, opts.Isolation)
+	}
+
+	// MACHINE_TYPE may name a built-in or a user-defined type
+	if _, err := opts.ResolveMachineType(); err != nil {
+		return nil, fmt.Errorf("invalid MACHINE_TYPE: %w", err)
+	}
+
+	joinDokployNetwork, err := getEnvBool(getenv, "DOKPLOY_JOIN_DOKPLOY_NETWORK", false)
+	if err != nil {
+		return nil, err
+	}
+	opts.JoinDokployNetwork = joinDokployNetwork
+	opts.AllowedServices = getEnvList(getenv, "DOKPLOY_ALLOWED_SERVICES")
+
+	if opts.SharedCache, err = getEnvBool(getenv, "DOKPLOY_SHARED_CACHE", false); err != nil {
+		return nil, err
+	}
+
+	if opts.DockerMTU, err = getEnvInt(getenv, "DOKPLOY_DOCKER_MTU", 0); err != nil {
+		return nil, err
+	}
+	if opts.DockerLogMaxFile, err = getEnvInt(getenv, "DOKPLOY_DOCKER_LOG_MAX_FILE", 0); err != nil {
+		return nil, err
 	}
 
 	// Validate required options
@@ -42,10 +171,104 @@ func LoadFromEnv() (*Options, error) {
 	return opts, nil
 }
 
+// SidecarDefinition returns the sidecar services YAML. DOKPLOY_SIDECARS may hold the YAML
+// itself or the path to a file containing it.
+func (o *Options) SidecarDefinition() (string, error) {
+	return valueOrFile(o.Sidecars, "sidecars")
+}
+
+// PostSetupScript returns the post-setup hook. DOKPLOY_POST_SETUP_SCRIPT may hold the script
+// itself or the path to a file containing it.
+func (o *Options) PostSetupScript() (string, error) {
+	return valueOrFile(o.PostSetupScriptValue, "post-setup script")
+}
+
+// valueOrFile returns the contents of the file named by value when it is a path to an
+// existing file, and value itself otherwise
+func valueOrFile(value, description string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.Contains(value, "\n") {
+		return value, nil
+	}
+
+	if info, err := os.Stat(value); err == nil && !info.IsDir() {
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s file %s: %w", description, value, err)
+		}
+		return string(data), nil
+	}
+
+	return value, nil
+}
+
 // getEnvWithDefault returns the environment variable value or a default value
-func getEnvWithDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+func getEnvWithDefault(getenv func(string) string, key, defaultValue string) string {
+	if value := getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
-} 
\ No newline at end of file
+}
+
+// getEnvBool parses a boolean environment variable, returning the default when unset
+func getEnvBool(getenv func(string) string, key string, defaultValue bool) (bool, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got '%s'", key, value)
+	}
+	return parsed, nil
+}
+
+// getEnvInt parses an integer environment variable, returning the default when unset
+func getEnvInt(getenv func(string) string, key string, defaultValue int) (int, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a number, got '%s'", key, value)
+	}
+	return parsed, nil
+}
+
+// getEnvDuration parses a duration environment variable (e.g. 5m), returning the default when unset
+func getEnvDuration(getenv func(string) string, key string, defaultValue time.Duration) (time.Duration, error) {
+	value := getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s must be a duration such as 5m, got '%s'", key, value)
+	}
+	return parsed, nil
+}
+
+// getEnvPositiveDuration parses a duration environment variable that must be greater than zero,
+// such as a timeout, returning the default when unset
+func getEnvPositiveDuration(getenv func(string) string, key string, defaultValue time.Duration) (time.Duration, error) {
+	parsed, err := getEnvDuration(getenv, key, defaultValue)
+	if err != nil {
+		return 0, err
+	}
+	if parsed == 0 {
+		return 0, fmt.Errorf("%s must be greater than 0, got '%s'", key, getenv(key))
+	}
+	return parsed, nil
+}
+
+// getEnvList parses a comma-separated environment variable, ignoring empty entries
+func getEnvList(getenv func(string) string, key string) []string {
+	var values []string
+	for _, value := range strings.Split(getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
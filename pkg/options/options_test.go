@@ -0,0 +1,119 @@
+package options
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// setRequiredEnv sets the options LoadFromEnv requires
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("DOKPLOY_SERVER_URL", "https://dokploy.example.com")
+	t.Setenv("DOKPLOY_API_TOKEN", "token")
+}
+
+func TestLoadFromEnvTimeouts(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		wantErr bool
+	}{
+		{name: "start timeout", key: "DOKPLOY_START_TIMEOUT", value: "10m"},
+		{name: "zero start timeout", key: "DOKPLOY_START_TIMEOUT", value: "0", wantErr: true},
+		{name: "zero stop timeout", key: "DOKPLOY_STOP_TIMEOUT", value: "0s", wantErr: true},
+		{name: "negative stop timeout", key: "DOKPLOY_STOP_TIMEOUT", value: "-1m", wantErr: true},
+		{name: "invalid stop timeout", key: "DOKPLOY_STOP_TIMEOUT", value: "soon", wantErr: true},
+		{name: "inactivity timeout disabled", key: "DOKPLOY_INACTIVITY_TIMEOUT", value: "0"},
+		{name: "inactivity timeout", key: "DOKPLOY_INACTIVITY_TIMEOUT", value: "2h"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv(tt.key, tt.value)
+			_, err := LoadFromEnv()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadFromEnv() with %s=%s error = %v, wantErr %v", tt.key, tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvDefaults(t *testing.T) {
+	setRequiredEnv(t)
+	opts, err := LoadFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.StartTimeout != 5*time.Minute || opts.StopTimeout != 2*time.Minute || opts.InactivityTimeout != 0 {
+		t.Errorf("timeouts = %v, %v, %v, want 5m, 2m, 0", opts.StartTimeout, opts.StopTimeout, opts.InactivityTimeout)
+	}
+	if opts.MachineType != "small" || opts.Isolation != IsolationPrivileged || opts.WorkspaceImage != DefaultWorkspaceImage {
+		t.Errorf("defaults = %s, %s, %s", opts.MachineType, opts.Isolation, opts.WorkspaceImage)
+	}
+}
+
+func TestLoadFromEnvValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		val  string
+	}{
+		{name: "missing server URL", key: "DOKPLOY_SERVER_URL", val: ""},
+		{name: "unknown isolation", key: "DOKPLOY_ISOLATION", val: "vm"},
+		{name: "invalid boolean", key: "DOKPLOY_SHARED_CACHE", val: "maybe"},
+		{name: "invalid number", key: "DOKPLOY_DOCKER_MTU", val: "big"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRequiredEnv(t)
+			t.Setenv(tt.key, tt.val)
+			if _, err := LoadFromEnv(); err == nil {
+				t.Errorf("LoadFromEnv() with %s=%q succeeded", tt.key, tt.val)
+			}
+		})
+	}
+}
+
+func TestGetEnvList(t *testing.T) {
+	t.Setenv("TEST_LIST", " a, ,b ,c,")
+	if got := getEnvList(os.Getenv, "TEST_LIST"); !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Errorf("getEnvList() = %q", got)
+	}
+	t.Setenv("TEST_LIST", "")
+	if got := getEnvList(os.Getenv, "TEST_LIST"); got != nil {
+		t.Errorf("getEnvList() of an empty variable = %q, want nil", got)
+	}
+}
+
+func TestValueOrFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(path, []byte("echo from file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "empty", value: "  ", want: ""},
+		{name: "inline", value: "echo inline", want: "echo inline"},
+		{name: "multi-line inline", value: "echo a\necho b\n", want: "echo a\necho b"},
+		{name: "file", value: path, want: "echo from file\n"},
+		{name: "missing file is a value", value: path + ".missing", want: path + ".missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := valueOrFile(tt.value, "test")
+			if err != nil || got != tt.want {
+				t.Errorf("valueOrFile() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
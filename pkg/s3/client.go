@@ -0,0 +1,106 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Client is a client for S3-compatible object storage
+type Client struct {
+	minio *minio.Client
+}
+
+// NewClientFromEnv creates a client from the standard AWS environment variables. The endpoint
+// selects an S3-compatible service (e.g. MinIO) addressed path-style; when empty,
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL are used, and AWS S3 otherwise.
+func NewClientFromEnv(endpoint string) (*Client, error) {
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for S3")
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	minioOpts := &minio.Options{
+		Creds:        credentials.NewStaticV4(accessKey, secretKey, os.Getenv("AWS_SESSION_TOKEN")),
+		Secure:       true,
+		Region:       region,
+		BucketLookup: minio.BucketLookupDNS,
+	}
+	host := fmt.Sprintf("s3.%s.amazonaws.com", region)
+
+	for _, candidate := range []string{endpoint, os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")} {
+		if candidate == "" {
+			continue
+		}
+		parsed, err := url.Parse(candidate)
+		if err != nil || parsed.Host == "" || strings.Trim(parsed.Path, "/") != "" {
+			return nil, fmt.Errorf("invalid S3 endpoint '%s' (expected a URL like https://minio.example.com:9000)", candidate)
+		}
+		host = parsed.Host
+		minioOpts.Secure = parsed.Scheme != "http"
+		minioOpts.BucketLookup = minio.BucketLookupPath
+		break
+	}
+
+	client, err := minio.New(host, minioOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return &Client{minio: client}, nil
+}
+
+// IsURL reports whether a snapshot location is an s3:// URL
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, "s3://")
+}
+
+// ParseURL splits an s3://bucket/key URL into bucket and key
+func ParseURL(location string) (bucket, key string, err error) {
+	rest := strings.TrimPrefix(location, "s3://")
+	bucket, key, found := strings.Cut(rest, "/")
+	if !found || bucket == "" || key == "" {
+		return "", "", fmt.Errorf("invalid S3 URL '%s' (expected s3://bucket/key)", location)
+	}
+	return bucket, key, nil
+}
+
+// PutObject uploads size bytes from body, or the whole stream when size is -1. Large objects
+// are uploaded in parts, so there is no limit below the 5 TB maximum object size of S3.
+func (c *Client) PutObject(bucket, key string, body io.Reader, size int64) error {
+	_, err := c.minio.PutObject(context.Background(), bucket, key, body, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// GetObject downloads an object. The caller closes the returned body.
+func (c *Client) GetObject(bucket, key string) (io.ReadCloser, error) {
+	object, err := c.minio.GetObject(context.Background(), bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download s3://%s/%s: %w", bucket, key, err)
+	}
+	// The request is only sent on the first read; stat it so that a missing object fails here
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, fmt.Errorf("failed to download s3://%s/%s: %w", bucket, key, err)
+	}
+	return object, nil
+}
@@ -0,0 +1,225 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeS3 is an in-memory S3 server with just enough of the API for uploads and downloads
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	parts   map[string]map[int][]byte
+	puts    int // number of part uploads
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	path := r.URL.Path
+	query := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		f.parts[path] = map[int][]byte{}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>b</Bucket><Key>k</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		body := readBody(r)
+		f.parts[path][number] = body
+		f.puts++
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		var numbers []int
+		for number := range f.parts[path] {
+			numbers = append(numbers, number)
+		}
+		sort.Ints(numbers)
+		var object []byte
+		for _, number := range numbers {
+			object = append(object, f.parts[path][number]...)
+		}
+		f.objects[path] = object
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>b</Bucket><Key>k</Key><ETag>%s</ETag></CompleteMultipartUploadResult>`, etag(object))
+	case r.Method == http.MethodPut:
+		body := readBody(r)
+		f.objects[path] = body
+		w.Header().Set("ETag", etag(body))
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		object, ok := f.objects[path]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		w.Header().Set("ETag", etag(object))
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(object)))
+		if r.Method == http.MethodGet {
+			w.Write(object)
+		}
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// readBody returns the request body, decoding the aws-chunked encoding of signed streams
+func readBody(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	if !strings.HasPrefix(r.Header.Get("x-amz-content-sha256"), "STREAMING-") {
+		return body
+	}
+
+	var decoded []byte
+	for len(body) > 0 {
+		header, rest, _ := bytes.Cut(body, []byte("\r\n"))
+		sizeHex, _, _ := bytes.Cut(header, []byte(";"))
+		size, err := strconv.ParseInt(string(sizeHex), 16, 64)
+		if err != nil || size == 0 || int64(len(rest)) < size {
+			break
+		}
+		decoded = append(decoded, rest[:size]...)
+		body = bytes.TrimPrefix(rest[size:], []byte("\r\n"))
+	}
+	return decoded
+}
+
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+// newTestClient returns a client for a fake S3 server
+func newTestClient(t *testing.T) (*Client, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: map[string][]byte{}, parts: map[string]map[int][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "access")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	client, err := NewClientFromEnv(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, fake
+}
+
+func TestPutAndGetObject(t *testing.T) {
+	large := bytes.Repeat([]byte("0123456789abcdef"), 20<<20/16)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		size      int64
+		wantParts bool
+	}{
+		{name: "small object", data: []byte("snapshot"), size: 8},
+		{name: "small stream", data: []byte("snapshot"), size: -1},
+		{name: "multipart object", data: large, size: int64(len(large)), wantParts: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := newTestClient(t)
+			if err := client.PutObject("bucket", "snapshots/ws.tar.zst", bytes.NewReader(tt.data), tt.size); err != nil {
+				t.Fatalf("PutObject() error = %v", err)
+			}
+			if (fake.puts > 1) != tt.wantParts {
+				t.Errorf("PutObject() uploaded %d parts, want multipart %v", fake.puts, tt.wantParts)
+			}
+
+			body, err := client.GetObject("bucket", "snapshots/ws.tar.zst")
+			if err != nil {
+				t.Fatalf("GetObject() error = %v", err)
+			}
+			defer body.Close()
+			got, err := io.ReadAll(body)
+			if err != nil || !bytes.Equal(got, tt.data) {
+				t.Errorf("GetObject() returned %d bytes, %v, want %d bytes", len(got), err, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestGetObjectMissing(t *testing.T) {
+	client, _ := newTestClient(t)
+	if _, err := client.GetObject("bucket", "missing.tar.zst"); err == nil {
+		t.Error("GetObject() of a missing object succeeded")
+	}
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		env      map[string]string
+		wantErr  bool
+	}{
+		{name: "AWS", env: map[string]string{"AWS_REGION": "eu-west-1"}},
+		{name: "endpoint", endpoint: "http://minio.local:9000"},
+		{name: "endpoint from environment", env: map[string]string{"AWS_ENDPOINT_URL_S3": "https://minio.example.com"}},
+		{name: "endpoint with path", endpoint: "https://minio.example.com/s3", wantErr: true},
+		{name: "endpoint without host", endpoint: "minio", wantErr: true},
+		{name: "missing credentials", env: map[string]string{"AWS_SECRET_ACCESS_KEY": ""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AWS_ACCESS_KEY_ID", "access")
+			t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+			t.Setenv("AWS_ENDPOINT_URL_S3", "")
+			t.Setenv("AWS_ENDPOINT_URL", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if _, err := NewClientFromEnv(tt.endpoint); (err != nil) != tt.wantErr {
+				t.Errorf("NewClientFromEnv(%q) error = %v, wantErr %v", tt.endpoint, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		location   string
+		wantBucket string
+		wantKey    string
+		wantErr    bool
+	}{
+		{location: "s3://bucket/ws.tar.zst", wantBucket: "bucket", wantKey: "ws.tar.zst"},
+		{location: "s3://bucket/snapshots/2026/ws.tar.zst", wantBucket: "bucket", wantKey: "snapshots/2026/ws.tar.zst"},
+		{location: "s3://bucket", wantErr: true},
+		{location: "s3://bucket/", wantErr: true},
+		{location: "s3:///ws.tar.zst", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			bucket, key, err := ParseURL(tt.location)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bucket != tt.wantBucket || key != tt.wantKey {
+				t.Errorf("ParseURL() = %q, %q, want %q, %q", bucket, key, tt.wantBucket, tt.wantKey)
+			}
+		})
+	}
+}
@@ -1,18 +1,114 @@
 #!/bin/bash
 set -e
 
+# Readiness markers checked by the container healthcheck; reset on every start
+POST_SETUP_LOG=/var/log/devpod-post-setup.log
+PROGRESS_FILE=/run/devpod-progress.json
+rm -f /run/devpod-ready /run/devpod-post-setup.failed "$PROGRESS_FILE"
+
+SETUP_STAGE=0
+SETUP_TOTAL=4
+SETUP_MESSAGE="starting setup"
+
+json_escape() {
+  printf '%s' "$1" | sed -e 's/\\/\\\\/g' -e 's/"/\\"/g' | tr '\n\t' '  '
+}
+
+# progress writes the machine-readable setup progress that the provider reads through the healthcheck
+progress() {
+  printf '{"stage":%d,"total":%d,"status":"%s","message":"%s","error":"%s","timestamp":"%s"}\n' \
+    "$SETUP_STAGE" "$SETUP_TOTAL" "$1" "$(json_escape "$SETUP_MESSAGE")" "$(json_escape "${2:-}")" \
+    "$(date -u +%Y-%m-%dT%H:%M:%SZ)" > "$PROGRESS_FILE.tmp"
+  mv "$PROGRESS_FILE.tmp" "$PROGRESS_FILE"
+}
+
+# stage starts a setup stage: stage <number> <message>
+stage() {
+  SETUP_STAGE="$1"
+  SETUP_MESSAGE="$2"
+  echo "Stage $SETUP_STAGE/$SETUP_TOTAL: $SETUP_MESSAGE..."
+  progress running
+}
+
+# fail records the error and keeps the container running, so the provider can report it
+# instead of the container restarting in a loop
+fail() {
+  trap - ERR
+  echo "ERROR: $1"
+  progress failed "$1"
+  echo "Setup failed at stage $SETUP_STAGE/$SETUP_TOTAL ($SETUP_MESSAGE); waiting for the workspace to be fixed or deleted"
+  tail -f /dev/null
+}
+
+trap 'fail "setup command failed at line $LINENO (exit code $?)"' ERR
+progress running
+
 # Get SSH public key from environment variable
 if [ -z "$SSH_PUBLIC_KEY" ]; then
-  echo "ERROR: SSH_PUBLIC_KEY environment variable is not set"
-  exit 1
+  fail "SSH_PUBLIC_KEY environment variable is not set"
 fi
 
 echo "🐳 DOKPLOY DEVPOD PROVIDER - Docker Compose with Privileged Mode (ROOT MODE)"
 echo "============================================================================"
 
-echo "Stage 1/4: Starting Docker daemon using DinD built-in script..."
-# Start docker using the built-in DinD script
-start-docker.sh &
+# Inner Docker daemon configuration (registry mirrors, address pools, MTU, logging)
+DOCKERD_CONFIG_ARGS=""
+if [ -n "$DOCKER_DAEMON_CONFIG" ]; then
+  echo "Writing /etc/docker/daemon.json..."
+  mkdir -p /etc/docker
+  echo "$DOCKER_DAEMON_CONFIG" | base64 -d > /etc/docker/daemon.json
+  cat /etc/docker/daemon.json
+  if ! dockerd --validate --config-file /etc/docker/daemon.json; then
+    fail "dockerd rejected /etc/docker/daemon.json; check the DOKPLOY_DOCKER_* provider options"
+  fi
+  DOCKERD_CONFIG_ARGS="--config-file /etc/docker/daemon.json"
+  echo "✓ Docker daemon configuration written and validated"
+fi
+
+if [ "$DEVPOD_ISOLATION" = "rootless" ]; then
+  stage 1 "starting rootless Docker daemon"
+  # Rootless dockerd needs unprivileged user namespaces on the node
+  if ! unshare --user --map-root-user true >/dev/null 2>&1; then
+    fail "user namespaces are not available: DOKPLOY_ISOLATION=rootless requires unprivileged user namespaces on the Dokploy node (kernel.unprivileged_userns_clone=1), or use DOKPLOY_ISOLATION=sysbox"
+  fi
+
+  # The rootless extras are baked into the image (dokploy-provider build-image)
+  if ! command -v dockerd-rootless.sh >/dev/null 2>&1; then
+    fail "dockerd-rootless.sh not found: DOKPLOY_ISOLATION=rootless needs an image built with dokploy-provider build-image"
+  fi
+  if command -v newuidmap >/dev/null 2>&1 && command -v iptables >/dev/null 2>&1; then
+    echo "✓ Rootless prerequisites already installed (prebuilt image)"
+  else
+    apt-get update -qq
+    apt-get install -y -qq uidmap iptables
+  fi
+
+  # Unprivileged user that owns the inner Docker daemon
+  if ! id rootless >/dev/null 2>&1; then
+    useradd --create-home --uid 1000 rootless
+    echo "rootless:100000:65536" >> /etc/subuid
+    echo "rootless:100000:65536" >> /etc/subgid
+  fi
+  mkdir -p /run/user/1000 /var/lib/docker/rootless
+  chown rootless:rootless /run/user/1000 /var/lib/docker/rootless
+  chmod 700 /run/user/1000
+
+  su rootless -c "XDG_RUNTIME_DIR=/run/user/1000 PATH=/usr/local/bin:\$PATH dockerd-rootless.sh --data-root /var/lib/docker/rootless $DOCKERD_CONFIG_ARGS" \
+    > /var/log/dockerd-rootless.log 2>&1 &
+
+  # Expose the rootless socket at the default location for root and the DevPod agent
+  for i in $(seq 1 30); do
+    if [ -S /run/user/1000/docker.sock ]; then
+      ln -sf /run/user/1000/docker.sock /var/run/docker.sock
+      break
+    fi
+    sleep 1
+  done
+else
+  stage 1 "starting Docker daemon"
+  # Start docker using the built-in DinD script
+  start-docker.sh &
+fi
 
 # Wait for Docker daemon to be ready
 echo "Waiting for Docker daemon to start..."
@@ -22,25 +118,35 @@ for i in $(seq 1 30); do
     break
   fi
   if [ $i -eq 30 ]; then
-    echo "ERROR: Docker daemon failed to start"
-    exit 1
+    if [ -f /etc/docker/daemon.json ]; then
+      echo "ERROR: dockerd may have rejected /etc/docker/daemon.json:"
+      cat /etc/docker/daemon.json
+    fi
+    if [ -f /var/log/dockerd-rootless.log ]; then
+      tail -n 50 /var/log/dockerd-rootless.log
+    fi
+    fail "Docker daemon failed to start within 30 seconds"
   fi
   sleep 1
 done
 
-echo "Stage 2/4: Installing SSH server and tools..."
-apt-get update -qq
-apt-get install -y -qq openssh-server sudo curl wget ca-certificates gnupg
-echo "✓ SSH server and tools installed"
+stage 2 "installing sshd and tools"
+if command -v sshd >/dev/null 2>&1 && command -v sudo >/dev/null 2>&1 && command -v curl >/dev/null 2>&1; then
+  echo "✓ SSH server and tools already installed (prebuilt image), skipping installation"
+else
+  apt-get update -qq
+  apt-get install -y -qq __PACKAGES_PLACEHOLDER__
+  echo "✓ SSH server and tools installed"
+fi
 
-echo "Stage 3/4: Setting up SSH keys for root user..."
+stage 3 "setting up SSH keys"
 mkdir -p /root/.ssh
 echo "$SSH_PUBLIC_KEY" > /root/.ssh/authorized_keys
 chmod 700 /root/.ssh
 chmod 600 /root/.ssh/authorized_keys
 echo "✓ SSH keys configured for root"
 
-echo "Stage 4/4: Configuring SSH daemon..."
+stage 4 "configuring SSH daemon"
 echo "Port 22" > /etc/ssh/sshd_config
 echo "PubkeyAuthentication yes" >> /etc/ssh/sshd_config
 echo "AuthorizedKeysFile .ssh/authorized_keys" >> /etc/ssh/sshd_config
@@ -54,17 +160,135 @@ echo "AcceptEnv LANG LC_*" >> /etc/ssh/sshd_config
 echo "Subsystem sftp /usr/lib/openssh/sftp-server" >> /etc/ssh/sshd_config
 echo "✓ SSH daemon configured (root access enabled)"
 
+# Keep the host keys in their own volume so that recreating the container (e.g. on resize)
+# does not change the workspace's SSH identity
+if ls /var/lib/devpod-ssh/ssh_host_*_key >/dev/null 2>&1; then
+  cp -p /var/lib/devpod-ssh/ssh_host_* /etc/ssh/
+  echo "✓ SSH host keys restored"
+else
+  # Prebuilt images ship the keys generated at build time; give each workspace its own
+  rm -f /etc/ssh/ssh_host_*
+  ssh-keygen -A
+  mkdir -p /var/lib/devpod-ssh
+  cp -p /etc/ssh/ssh_host_* /var/lib/devpod-ssh/
+  echo "✓ SSH host keys saved"
+fi
+
 service ssh start
 echo "✓ SSH daemon started"
 
+# User hooks: dotfiles repository and post-setup script, logged to $POST_SETUP_LOG
+if [ -n "$DEVPOD_DOTFILES_URL" ] || [ -n "$DEVPOD_POST_SETUP_SCRIPT" ]; then
+  SETUP_MESSAGE="running post-setup hooks"
+  progress running
+  echo "Running post-setup hooks (log: $POST_SETUP_LOG)..."
+  : > "$POST_SETUP_LOG"
+  # The hooks run in a subshell with errexit as a plain statement: as the condition of an if,
+  # bash would ignore set -e inside it. The ERR trap is suspended so a failure is not fatal.
+  trap - ERR
+  set +e
+  (
+    set -e
+    if [ -n "$DEVPOD_DOTFILES_URL" ]; then
+      echo "==> Installing dotfiles from $DEVPOD_DOTFILES_URL"
+      if ! command -v git >/dev/null 2>&1; then
+        apt-get update -qq
+        apt-get install -y -qq git
+      fi
+      if [ -d /root/.dotfiles/.git ]; then
+        git -C /root/.dotfiles pull --ff-only
+      else
+        git clone --depth 1 "$DEVPOD_DOTFILES_URL" /root/.dotfiles
+      fi
+      installed=""
+      for installer in install.sh install bootstrap.sh bootstrap setup.sh setup; do
+        if [ -f "/root/.dotfiles/$installer" ]; then
+          echo "==> Running $installer"
+          (cd /root/.dotfiles && HOME=/root bash "./$installer")
+          installed="yes"
+          break
+        fi
+      done
+      if [ -z "$installed" ]; then
+        echo "==> No install script found, linking dotfiles into /root"
+        for file in /root/.dotfiles/.[!.]*; do
+          case "$(basename "$file")" in
+            .git|.github|.gitignore|.gitmodules) continue ;;
+          esac
+          ln -sfn "$file" "/root/$(basename "$file")"
+        done
+      fi
+    fi
+    if [ -n "$DEVPOD_POST_SETUP_SCRIPT" ]; then
+      echo "==> Running post-setup script"
+      echo "$DEVPOD_POST_SETUP_SCRIPT" | base64 -d > /usr/local/bin/devpod-post-setup.sh
+      chmod +x /usr/local/bin/devpod-post-setup.sh
+      (cd /workspace && HOME=/root bash -e /usr/local/bin/devpod-post-setup.sh)
+    fi
+  ) >> "$POST_SETUP_LOG" 2>&1
+  post_setup_status=$?
+  set -e
+  trap 'fail "setup command failed at line $LINENO (exit code $?)"' ERR
+  if [ "$post_setup_status" -eq 0 ]; then
+    echo "✓ Post-setup hooks completed"
+  else
+    echo "ERROR: Post-setup hook failed, last lines of $POST_SETUP_LOG:"
+    tail -n 20 "$POST_SETUP_LOG"
+    touch /run/devpod-post-setup.failed
+    progress failed "post-setup hook failed (exit code $post_setup_status), see $POST_SETUP_LOG"
+  fi
+fi
+
 echo ""
 echo "🎉 WORKSPACE READY (ROOT MODE)!"
-echo "✓ Docker daemon: Running (privileged mode)"
+echo "✓ Docker daemon: Running (${DEVPOD_ISOLATION:-privileged} isolation)"
 echo "✓ SSH daemon: Running on port 22"
 echo "✓ User: root with full access"
 echo "✓ Docker access: Full Docker-in-Docker capability"
 echo "✓ Development environment: Ready for DevPod"
+if [ -f /run/devpod-post-setup.failed ]; then
+  echo "⚠ Post-setup hook failed: the workspace stays unhealthy until it is fixed and the workspace restarted"
+else
+  SETUP_MESSAGE="ready"
+  progress ready
+  touch /run/devpod-ready
+fi
 echo ""
 
+# Inactivity auto-stop: stop the workspace through the Dokploy API once no SSH session is left
+if [ "${DEVPOD_INACTIVITY_TIMEOUT:-0}" -gt 0 ] && [ -n "$DEVPOD_INACTIVITY_TOKEN" ]; then
+  cat > /usr/local/bin/devpod-idle-watcher <<'WATCHER'
+#!/bin/bash
+# Stops the workspace after DEVPOD_INACTIVITY_TIMEOUT seconds without SSH sessions or DevPod tunnels
+last_active=$(date +%s)
+while true; do
+  sleep 60
+  # Established connections to sshd (port 22 = 0016, state 01) or running DevPod agent tunnels
+  if awk 'FNR > 1 && $4 == "01" && $2 ~ /:0016$/ { found = 1 } END { exit !found }' /proc/net/tcp /proc/net/tcp6 2>/dev/null \
+    || pgrep -f "devpod.*(ssh-server|container-tunnel)" >/dev/null 2>&1; then
+    last_active=$(date +%s)
+    continue
+  fi
+
+  idle=$(( $(date +%s) - last_active ))
+  if [ "$idle" -lt "$DEVPOD_INACTIVITY_TIMEOUT" ]; then
+    continue
+  fi
+
+  echo "$(date -u +%Y-%m-%dT%H:%M:%SZ) idle for ${idle}s, stopping workspace $DEVPOD_COMPOSE_ID"
+  if curl -fsS -X POST "$DEVPOD_DOKPLOY_URL/api/compose.stop" \
+    -H "x-api-key: $DEVPOD_INACTIVITY_TOKEN" -H "Content-Type: application/json" \
+    -d "{\"composeId\":\"$DEVPOD_COMPOSE_ID\"}"; then
+    exit 0
+  fi
+  echo "$(date -u +%Y-%m-%dT%H:%M:%SZ) stop request failed, retrying in 5 minutes"
+  last_active=$(( $(date +%s) - DEVPOD_INACTIVITY_TIMEOUT + 300 ))
+done
+WATCHER
+  chmod +x /usr/local/bin/devpod-idle-watcher
+  nohup /usr/local/bin/devpod-idle-watcher >> /var/log/devpod-idle-watcher.log 2>&1 &
+  echo "✓ Inactivity watcher started (stops the workspace after ${DEVPOD_INACTIVITY_TIMEOUT}s idle)"
+fi
+
 # Keep container running
 tail -f /dev/null 
\ No newline at end of file
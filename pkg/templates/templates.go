@@ -11,3 +11,13 @@ var DockerComposeTemplate string
 //
 //go:embed setup-root.sh
 var SetupScriptTemplate string
+
+// DockerfileTemplate contains the Dockerfile template for prebuilt workspace images
+//
+//go:embed Dockerfile
+var DockerfileTemplate string
+
+// CacheComposeTemplate contains the docker-compose.yml template of the shared image cache
+//
+//go:embed cache-compose.yml
+var CacheComposeTemplate string